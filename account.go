@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+
+	"github.com/marc825/netatmo-exporter/v2/internal/api"
+	"github.com/marc825/netatmo-exporter/v2/internal/backfill"
+	"github.com/marc825/netatmo-exporter/v2/internal/collector"
+	"github.com/marc825/netatmo-exporter/v2/internal/config"
+	"github.com/marc825/netatmo-exporter/v2/internal/token"
+	"github.com/marc825/netatmo-exporter/v2/internal/web"
+)
+
+// account contains the client and the cached data of a single Netatmo account. Every account has its own
+// API client, store and refresh schedule, so errors of one account do not affect the others.
+type account struct {
+	config.Account
+	log    logrus.FieldLogger
+	client *netatmo.Client
+	store  *collector.Store
+}
+
+// authPath returns the path of the authentication handlers of the account.
+func (a *account) authPath() string {
+	if a.Name == "" {
+		return "/auth"
+	}
+
+	return "/auth/" + a.Name
+}
+
+// debugPath returns the path of the debugging handlers of the account.
+func (a *account) debugPath() string {
+	if a.Name == "" {
+		return "/debug"
+	}
+
+	return "/debug/" + a.Name
+}
+
+// stateFile returns the path of a state file of the account, which is saved next to the token file.
+func (a *account) stateFile(name string) string {
+	if a.Name != "" {
+		name += "-" + a.Name
+	}
+
+	return filepath.Join(filepath.Dir(a.TokenFile), name+".json")
+}
+
+// registerers returns the registerers for the V1 and V2 metrics of the account. If multiple accounts are
+// used, all metrics of the account get an "account" label.
+func (a *account) registerers(registryV1, registryV2 prometheus.Registerer) (prometheus.Registerer, prometheus.Registerer) {
+	if a.Name == "" {
+		return registryV1, registryV2
+	}
+
+	labels := prometheus.Labels{"account": a.Name}
+	return prometheus.WrapRegistererWith(labels, registryV1), prometheus.WrapRegistererWith(labels, registryV2)
+}
+
+// setupAccount creates the client of an account, registers its collectors and handlers and starts the
+// background refresh of its data. The app budget is shared by all accounts using the same client ID.
+// Public weather stations are only read if public is set.
+func setupAccount(ctx context.Context, cfg config.Config, accountCfg config.Account, appBudget *api.Budget, public bool, registryV1, registryV2 prometheus.Registerer) *account {
+	a := &account{
+		Account: accountCfg,
+		log:     log,
+	}
+	if a.Name != "" {
+		a.log = log.WithField("account", a.Name)
+	}
+	accountV1, accountV2 := a.registerers(registryV1, registryV2)
+
+	// Instrumented and rate-limited client for all requests of the account to the Netatmo API
+	apiInstrumentation := api.NewInstrumentation(http.DefaultTransport)
+	apiTransport := api.NewTransport(a.log, apiInstrumentation,
+		api.NewBudget("user", api.UserLimits),
+		appBudget,
+	)
+	apiClient := &http.Client{Transport: apiTransport}
+
+	// Token requests of the Netatmo client use the API client as well
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, apiClient)
+
+	// Netatmo API client
+	a.client = netatmo.NewClient(a.Netatmo, tokenUpdated(a.log, a.TokenFile))
+
+	// Load token from file if available
+	restored, err := loadToken(a.TokenFile)
+	switch {
+	case os.IsNotExist(err):
+		// no token file yet
+	case err != nil:
+		a.log.Fatalf("Error loading token: %s", err)
+	case !restored.Expiry.IsZero() && restored.Expiry.Before(time.Now()):
+		a.log.Warn("Restored token has expired! Token has been ignored.")
+	default:
+		if restored.RefreshToken == "" {
+			a.log.Warn("Restored token has no refresh-token! Exporter will need to be re-authenticated manually.")
+		} else if restored.Expiry.IsZero() {
+			a.log.Warn("Restored token has no expiry time! Token will be renewed immediately.")
+			restored.Expiry = time.Now().Add(time.Second)
+		}
+
+		a.log.Infof("Loaded token from %s.", a.TokenFile)
+		a.client.InitWithToken(ctx, restored)
+	}
+
+	var weatherReader collector.WeatherReadFunction
+	var homecoachReader collector.HomecoachReadFunction
+	var homesReader collector.HomesReadFunction
+
+	if cfg.EnableWeather {
+		weatherReader = collector.NewWeatherReadFunction(a.client.CurrentToken, apiClient, cfg.WeatherFavorites)
+	}
+
+	if cfg.EnableHomecoach {
+		homecoachReader = collector.NewHomecoachReadFunction(a.client.CurrentToken, apiClient)
+	}
+
+	// Energy, Security, Detector and Home+Control share the homes read from homesdata and homestatus
+	if cfg.EnableEnergy || cfg.EnableSecurity || cfg.EnableDetector || cfg.EnableHomeControl {
+		readEvents := cfg.EnableSecurity || cfg.EnableDetector
+		homesReader = collector.NewHomesReadFunction(a.client.CurrentToken, apiClient, readEvents)
+	}
+
+	// Shared data store used by all collectors and the debug handler of the account
+	a.store = collector.NewStore(a.log, weatherReader, homecoachReader, homesReader, cfg.RefreshInterval)
+	a.store.SetHomecoachHomes(cfg.HomecoachHomes)
+
+	// Public weather stations in the configured area, refreshed using their own interval
+	if public {
+		area := collector.BoundingBox{
+			LatSW: cfg.PublicArea[0],
+			LonSW: cfg.PublicArea[1],
+			LatNE: cfg.PublicArea[2],
+			LonNE: cfg.PublicArea[3],
+		}
+		a.store.EnablePublic(collector.NewPublicReadFunction(a.client.CurrentToken, apiClient, area), cfg.PublicInterval)
+		a.log.Infof("Public weather stations enabled for area %v.", cfg.PublicArea)
+	}
+
+	// Backfill measurements missed between two successful refreshes
+	if cfg.Backfill != "" {
+		var sink backfill.Sink
+		switch cfg.Backfill {
+		case config.BackfillOpenMetrics:
+			sink = backfill.NewOpenMetricsSink(cfg.BackfillDirectory)
+		case config.BackfillRemoteWrite:
+			sink = backfill.NewRemoteWriteSink(cfg.BackfillRemoteWriteURL)
+		}
+
+		backfiller, err := backfill.New(a.log, a.store, a.client.CurrentToken, apiClient, sink, 2*cfg.RefreshInterval, cfg.WeatherFavorites, a.stateFile("netatmo-backfill"), a.Name)
+		if err != nil {
+			a.log.Fatalf("Error creating backfill: %s", err)
+		}
+
+		a.store.OnRefresh(backfiller.OnRefresh)
+		a.log.Infof("Backfilling of missed measurements enabled using %s.", cfg.Backfill)
+	}
+
+	// Home+Control collector V2, which updates its energy counters after every refresh
+	var homeControlCollector *collector.HomeControlCollector
+	if cfg.EnableHomeControl {
+		homeControlCollector = collector.NewHomeControlCollector(a.log, a.store, collector.NewEnergyMeasureFunction(a.client.CurrentToken, apiClient))
+		a.store.OnRefresh(homeControlCollector.OnRefresh)
+	}
+
+	// Battery replacements and firmware changes V2, detected by comparing the devices between refreshes
+	eventCollector, err := collector.NewEventCollector(a.log, a.store, a.stateFile("netatmo-events"))
+	if err != nil {
+		a.log.Fatalf("Error creating event collector: %s", err)
+	}
+	a.store.OnRefresh(eventCollector.OnRefresh)
+
+	// Unified collector V2 for Weather + HomeCoach, which keeps the battery voltages of recent refreshes
+	unifiedCollector := collector.UnifiedCollector(a.log, a.store, cfg.StaleDuration, collector.StalePolicy(cfg.StalePolicy), cfg.WeatherFavorites, collector.UnitSystem(cfg.Units))
+	a.store.OnRefresh(unifiedCollector.OnRefresh)
+
+	// Background refresh of the store, independent of scrapes
+	scheduler := collector.NewScheduler(a.log)
+	a.store.Schedule(scheduler)
+	go scheduler.Run(ctx)
+
+	// Weather station collector V1
+	if cfg.EnableWeather {
+		weatherMetrics := collector.NewWeatherCollector(a.log, a.store, cfg.StaleDuration, collector.StalePolicy(cfg.StalePolicy), collector.UnitSystem(cfg.Units))
+		accountV1.MustRegister(weatherMetrics)
+	}
+
+	// HomeCoach collector V1
+	if cfg.EnableHomecoach {
+		homecoachMetrics := collector.NewHomecoachCollector(a.log, a.store, cfg.StaleDuration, collector.StalePolicy(cfg.StalePolicy), collector.UnitSystem(cfg.Units))
+		accountV1.MustRegister(homecoachMetrics)
+	}
+
+	// Token metrics for V1 + V2
+	tokenMetric := token.Metric(a.client.CurrentToken)
+	accountV1.MustRegister(tokenMetric)
+	accountV2.MustRegister(tokenMetric)
+
+	// API client metrics for V1 + V2
+	accountV1.MustRegister(apiTransport, apiInstrumentation)
+	accountV2.MustRegister(apiTransport, apiInstrumentation)
+
+	accountV2.MustRegister(unifiedCollector, eventCollector)
+
+	// Derived metrics V2, calculated from the Weather + HomeCoach measurements
+	if cfg.DerivedMetrics {
+		accountV2.MustRegister(collector.NewDerivedCollector(a.log, a.store, cfg.StaleDuration, collector.StalePolicy(cfg.StalePolicy), cfg.WeatherFavorites, cfg.OutdoorPairing))
+	}
+
+	// Energy collector V2
+	if cfg.EnableEnergy {
+		accountV2.MustRegister(collector.NewEnergyCollector(a.log, a.store))
+	}
+
+	// Security collector V2
+	if cfg.EnableSecurity {
+		accountV2.MustRegister(collector.NewSecurityCollector(a.log, a.store))
+	}
+
+	// Detector collector V2
+	if cfg.EnableDetector {
+		accountV2.MustRegister(collector.NewDetectorCollector(a.log, a.store, eventCollector))
+	}
+
+	if homeControlCollector != nil {
+		accountV2.MustRegister(homeControlCollector)
+	}
+
+	// Public weather stations collector V2
+	if a.store.PublicEnabled() {
+		accountV2.MustRegister(collector.NewPublicCollector(a.log, a.store, cfg.PublicStations))
+	}
+
+	if cfg.DebugHandlers {
+		// Combined debug handler for Weather + HomeCoach
+		var weatherData collector.WeatherReadFunction
+		if cfg.EnableWeather {
+			weatherData = a.store.WeatherData
+		}
+
+		var homecoachData collector.HomecoachReadFunction
+		if cfg.EnableHomecoach {
+			homecoachData = a.store.HomecoachData
+		}
+
+		http.Handle(a.debugPath()+"/netatmo", web.DebugNetatmoHandler(a.log, weatherData, homecoachData))
+		http.Handle(a.debugPath()+"/token", web.DebugTokenHandler(a.log, a.client.CurrentToken))
+	}
+
+	authPath := a.authPath()
+	http.Handle(authPath+"/authorize", web.AuthorizeHandler(cfg.ExternalURL, authPath, a.client, webFeatures(cfg)))
+	http.Handle(authPath+"/callback", web.CallbackHandler(ctx, a.client, a.log))
+	http.Handle(authPath+"/settoken", web.SetTokenHandler(ctx, a.client, a.log))
+	http.Handle(authPath+"/deletetoken", web.DeleteTokenHandler(ctx, a.client, a.TokenFile, a.log))
+
+	return a
+}
+
+// webFeatures returns the enabled collectors, which determine the scopes needed for authorization.
+func webFeatures(cfg config.Config) web.Features {
+	return web.Features{
+		Weather:     cfg.EnableWeather,
+		Homecoach:   cfg.EnableHomecoach,
+		Energy:      cfg.EnableEnergy,
+		Security:    cfg.EnableSecurity,
+		Detector:    cfg.EnableDetector,
+		HomeControl: cfg.EnableHomeControl,
+		Public:      len(cfg.PublicArea) > 0,
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// Limit describes the maximum number of requests allowed in a time window.
+type Limit struct {
+	Name     string
+	Requests int
+	Window   time.Duration
+}
+
+// Rate limits of the Netatmo API, see https://dev.netatmo.com/guideline#rate-limits
+var (
+	UserLimits = []Limit{
+		{Name: "10s", Requests: 50, Window: 10 * time.Second},
+		{Name: "1h", Requests: 500, Window: time.Hour},
+	}
+	AppLimits = []Limit{
+		{Name: "10s", Requests: 200, Window: 10 * time.Second},
+		{Name: "1h", Requests: 2000, Window: time.Hour},
+	}
+)
+
+// Budget keeps track of the requests made within the windows of its limits.
+type Budget struct {
+	name   string
+	limits []Limit
+
+	lock     sync.Mutex
+	requests []time.Time
+}
+
+// NewBudget creates a new Budget for the provided limits.
+func NewBudget(name string, limits []Limit) *Budget {
+	return &Budget{
+		name:   name,
+		limits: limits,
+	}
+}
+
+// Name returns the name of the budget.
+func (b *Budget) Name() string {
+	return b.name
+}
+
+// Limits returns the limits of the budget.
+func (b *Budget) Limits() []Limit {
+	return b.limits
+}
+
+// TryReserve records a request made at the given time, if it fits into all limits. Otherwise nothing is
+// recorded and the time at which the next request fits into all limits is returned.
+func (b *Budget) TryReserve(now time.Time) (time.Time, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.prune(now)
+
+	if available := b.available(now); available.After(now) {
+		return available, false
+	}
+
+	b.requests = append(b.requests, now)
+	return now, true
+}
+
+// release removes a request reserved at the given time, so that a request rejected by another budget does
+// not count towards this one.
+func (b *Budget) release(now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for i := len(b.requests) - 1; i >= 0; i-- {
+		if b.requests[i].Equal(now) {
+			b.requests = append(b.requests[:i], b.requests[i+1:]...)
+			return
+		}
+	}
+}
+
+// Remaining returns the number of requests left for each limit.
+func (b *Budget) Remaining(now time.Time) []int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.prune(now)
+
+	remaining := make([]int, len(b.limits))
+	for i, limit := range b.limits {
+		remaining[i] = limit.Requests - b.countSince(now.Add(-limit.Window))
+		if remaining[i] < 0 {
+			remaining[i] = 0
+		}
+	}
+
+	return remaining
+}
+
+// available returns the time at which the next request fits into all limits.
+func (b *Budget) available(now time.Time) time.Time {
+	available := now
+	for _, limit := range b.limits {
+		inWindow := b.countSince(now.Add(-limit.Window))
+		if inWindow < limit.Requests {
+			continue
+		}
+
+		// the oldest request in the window needs to leave the window first
+		oldest := b.requests[len(b.requests)-inWindow]
+		if next := oldest.Add(limit.Window); next.After(available) {
+			available = next
+		}
+	}
+
+	return available
+}
+
+func (b *Budget) countSince(start time.Time) int {
+	count := 0
+	for i := len(b.requests) - 1; i >= 0 && b.requests[i].After(start); i-- {
+		count++
+	}
+	return count
+}
+
+// prune removes requests which are older than the longest window.
+func (b *Budget) prune(now time.Time) {
+	var longest time.Duration
+	for _, limit := range b.limits {
+		if limit.Window > longest {
+			longest = limit.Window
+		}
+	}
+
+	start := now.Add(-longest)
+	i := 0
+	for i < len(b.requests) && !b.requests[i].After(start) {
+		i++
+	}
+	b.requests = b.requests[i:]
+}
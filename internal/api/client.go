@@ -0,0 +1,23 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// AuthenticatedClient creates a HTTP client which uses the current token for requests sent through the API client.
+func AuthenticatedClient(getCurrentToken func() (*oauth2.Token, error), apiClient *http.Client) (*http.Client, error) {
+	token, err := getCurrentToken()
+	if err != nil {
+		return nil, fmt.Errorf("getting token: %w", err)
+	}
+	if token == nil || !token.Valid() {
+		return nil, fmt.Errorf("token not available or invalid")
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, apiClient)
+	return oauth2.NewClient(ctx, oauth2.StaticTokenSource(token)), nil
+}
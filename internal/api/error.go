@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Error codes returned by the Netatmo API.
+const (
+	codeInvalidAccessToken = 2
+	codeAccessTokenExpired = 3
+	codeUserUsageReached   = 26
+)
+
+var (
+	// ErrTokenInvalid is returned when the API rejected the current token and no new token is available yet.
+	ErrTokenInvalid = errors.New("token rejected by API, waiting for a new token")
+)
+
+// Error contains an error response of the Netatmo API.
+type Error struct {
+	StatusCode int
+	Status     string
+	Code       int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("status %s", e.Status)
+	}
+
+	return fmt.Sprintf("status %s: %s (code %d)", e.Status, e.Message, e.Code)
+}
+
+// IsTokenError returns true if the error was caused by an invalid or expired access token.
+func (e *Error) IsTokenError() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.Code == codeInvalidAccessToken || e.Code == codeAccessTokenExpired
+}
+
+// IsRateLimit returns true if the error was caused by a rate limit.
+func (e *Error) IsRateLimit() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.Code == codeUserUsageReached
+}
+
+// SuspendedError is returned while requests are suspended because of a rate limit.
+type SuspendedError struct {
+	Until time.Time
+}
+
+func (e *SuspendedError) Error() string {
+	return fmt.Sprintf("requests suspended until %s", e.Until.Format(time.RFC3339))
+}
+
+// ResponseError creates an Error from an unsuccessful response. The body of the response is read,
+// but not closed.
+func ResponseError(resp *http.Response) *Error {
+	result := &Error{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+
+	var body struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+		result.Code = body.Error.Code
+		result.Message = body.Error.Message
+	}
+
+	return result
+}
+
+// peekError parses the error of a response while keeping the body readable for the caller.
+func peekError(resp *http.Response) *Error {
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return &Error{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+	}
+
+	return ResponseError(&http.Response{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       io.NopCloser(bytes.NewReader(data)),
+	})
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Error types used for classifying failed requests.
+const (
+	errorTypeAuth      = "auth"
+	errorTypeRateLimit = "rate_limit"
+	errorTypeNetwork   = "network"
+	errorTypeDecode    = "decode"
+	errorTypeHTTP5xx   = "http_5xx"
+)
+
+var errorTypes = []string{errorTypeAuth, errorTypeRateLimit, errorTypeNetwork, errorTypeDecode, errorTypeHTTP5xx}
+
+// Instrumentation is a http.RoundTripper which records metrics about every request sent to the Netatmo API.
+// The endpoint of a request is the last element of the URL path, for example "getstationsdata".
+type Instrumentation struct {
+	base http.RoundTripper
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+
+	lock      sync.Mutex
+	endpoints map[string]bool
+}
+
+// NewInstrumentation creates a new Instrumentation sending the requests through the base RoundTripper.
+func NewInstrumentation(base http.RoundTripper) *Instrumentation {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Instrumentation{
+		base: base,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prefix + "requests_total",
+			Help: "Number of requests sent to the Netatmo API by endpoint and status class.",
+		}, []string{"endpoint", "status_class"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    prefix + "request_duration_seconds",
+			Help:    "Duration of requests sent to the Netatmo API.",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"endpoint"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: prefix + "errors_total",
+			Help: "Number of failed requests to the Netatmo API by endpoint and cause (auth, rate_limit, network, decode, http_5xx).",
+		}, []string{"endpoint", "type"}),
+		endpoints: map[string]bool{},
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (i *Instrumentation) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := path.Base(req.URL.Path)
+	i.initEndpoint(endpoint)
+
+	start := time.Now()
+	resp, err := i.base.RoundTrip(req)
+	i.duration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		i.requests.WithLabelValues(endpoint, "error").Inc()
+		i.errors.WithLabelValues(endpoint, errorTypeNetwork).Inc()
+		return nil, err
+	}
+
+	i.requests.WithLabelValues(endpoint, statusClass(resp.StatusCode)).Inc()
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		i.errors.WithLabelValues(endpoint, errorTypeHTTP5xx).Inc()
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden, resp.StatusCode == http.StatusTooManyRequests:
+		apiErr := peekError(resp)
+		switch {
+		case apiErr.IsRateLimit():
+			i.errors.WithLabelValues(endpoint, errorTypeRateLimit).Inc()
+		case apiErr.IsTokenError(), resp.StatusCode == http.StatusUnauthorized:
+			i.errors.WithLabelValues(endpoint, errorTypeAuth).Inc()
+		}
+	}
+
+	resp.Body = &instrumentedBody{
+		ReadCloser: resp.Body,
+		decodeError: func() {
+			i.errors.WithLabelValues(endpoint, errorTypeDecode).Inc()
+		},
+	}
+	return resp, nil
+}
+
+// initEndpoint makes sure that the error counters of an endpoint are exported, even before the first error.
+func (i *Instrumentation) initEndpoint(endpoint string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.endpoints[endpoint] {
+		return
+	}
+
+	for _, errorType := range errorTypes {
+		i.errors.WithLabelValues(endpoint, errorType)
+	}
+	i.endpoints[endpoint] = true
+}
+
+// Describe implements prometheus.Collector
+func (i *Instrumentation) Describe(dChan chan<- *prometheus.Desc) {
+	i.requests.Describe(dChan)
+	i.duration.Describe(dChan)
+	i.errors.Describe(dChan)
+}
+
+// Collect implements prometheus.Collector
+func (i *Instrumentation) Collect(mChan chan<- prometheus.Metric) {
+	i.requests.Collect(mChan)
+	i.duration.Collect(mChan)
+	i.errors.Collect(mChan)
+}
+
+func statusClass(code int) string {
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// instrumentedBody allows reporting errors while decoding the body of a response.
+type instrumentedBody struct {
+	io.ReadCloser
+	decodeError func()
+}
+
+// ReportDecodeError records that the body of the response could not be decoded.
+// It has no effect if the response was not received through an Instrumentation.
+func ReportDecodeError(resp *http.Response) {
+	if body, ok := resp.Body.(*instrumentedBody); ok {
+		body.decodeError()
+	}
+}
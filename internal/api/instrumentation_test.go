@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentationClassifiesErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":{"code":2,"message":"Invalid access token"}}`))
+		case "/api/ratelimit":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":{"code":26,"message":"User usage reached"}}`))
+		case "/api/server":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte(`not json`))
+		}
+	}))
+	defer server.Close()
+
+	instrumentation := NewInstrumentation(nil)
+	client := &http.Client{Transport: instrumentation}
+
+	for _, endpoint := range []string{"auth", "ratelimit", "server"} {
+		resp, err := client.Get(server.URL + "/api/" + endpoint)
+		if err != nil {
+			t.Fatalf("got error %q", err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := client.Get(server.URL + "/api/decode")
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	var v interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		ReportDecodeError(resp)
+	}
+	resp.Body.Close()
+
+	tt := []struct {
+		endpoint  string
+		errorType string
+		want      float64
+	}{
+		{endpoint: "auth", errorType: errorTypeAuth, want: 1},
+		{endpoint: "auth", errorType: errorTypeRateLimit, want: 0},
+		{endpoint: "ratelimit", errorType: errorTypeRateLimit, want: 1},
+		{endpoint: "server", errorType: errorTypeHTTP5xx, want: 1},
+		{endpoint: "decode", errorType: errorTypeDecode, want: 1},
+		{endpoint: "decode", errorType: errorTypeNetwork, want: 0},
+	}
+
+	for _, tc := range tt {
+		got := testutil.ToFloat64(instrumentation.errors.WithLabelValues(tc.endpoint, tc.errorType))
+		if got != tc.want {
+			t.Errorf("got %v errors of type %s for %s, want %v", got, tc.errorType, tc.endpoint, tc.want)
+		}
+	}
+
+	if got := testutil.ToFloat64(instrumentation.requests.WithLabelValues("server", "5xx")); got != 1 {
+		t.Errorf("got %v 5xx requests, want 1", got)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	prefix = "netatmo_exporter_api_"
+)
+
+var (
+	budgetRemainingDesc = prometheus.NewDesc(
+		prefix+"budget_remaining_requests",
+		"Number of requests left in the rate limit window.",
+		[]string{"budget", "window"}, nil)
+
+	budgetLimitDesc = prometheus.NewDesc(
+		prefix+"budget_limit_requests",
+		"Number of requests allowed in the rate limit window.",
+		[]string{"budget", "window"}, nil)
+
+	retriesDesc = prometheus.NewDesc(
+		prefix+"retries_total",
+		"Number of retried requests.",
+		nil, nil)
+
+	rejectedDesc = prometheus.NewDesc(
+		prefix+"rejected_requests_total",
+		"Number of requests which were not sent (reason: budget, suspended, token_invalid).",
+		[]string{"reason"}, nil)
+
+	suspendedUntilDesc = prometheus.NewDesc(
+		prefix+"suspended_until_time",
+		"Set to the unix timestamp until which requests are suspended because of a rate limit. 0 if not suspended.",
+		nil, nil)
+
+	tokenRejectedDesc = prometheus.NewDesc(
+		prefix+"token_rejected",
+		"Set to 1 if requests are suspended because the API rejected the current token, 0 otherwise.",
+		nil, nil)
+
+	rejectReasons = []string{"budget", "suspended", "token_invalid"}
+)
+
+// Describe implements prometheus.Collector
+func (t *Transport) Describe(dChan chan<- *prometheus.Desc) {
+	dChan <- budgetRemainingDesc
+	dChan <- budgetLimitDesc
+	dChan <- retriesDesc
+	dChan <- rejectedDesc
+	dChan <- suspendedUntilDesc
+	dChan <- tokenRejectedDesc
+}
+
+// Collect implements prometheus.Collector
+func (t *Transport) Collect(mChan chan<- prometheus.Metric) {
+	now := t.clock()
+	for _, b := range t.budgets {
+		remaining := b.Remaining(now)
+		for i, limit := range b.Limits() {
+			mChan <- prometheus.MustNewConstMetric(budgetRemainingDesc, prometheus.GaugeValue, float64(remaining[i]), b.Name(), limit.Name)
+			mChan <- prometheus.MustNewConstMetric(budgetLimitDesc, prometheus.GaugeValue, float64(limit.Requests), b.Name(), limit.Name)
+		}
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	suspendedValue := 0.0
+	if now.Before(t.suspendedUntil) {
+		suspendedValue = float64(t.suspendedUntil.Unix())
+	}
+
+	tokenRejectedValue := 0.0
+	if t.rejectedToken != "" {
+		tokenRejectedValue = 1.0
+	}
+
+	mChan <- prometheus.MustNewConstMetric(retriesDesc, prometheus.CounterValue, float64(t.retries))
+	for _, reason := range rejectReasons {
+		mChan <- prometheus.MustNewConstMetric(rejectedDesc, prometheus.CounterValue, float64(t.rejectedRequests[reason]), reason)
+	}
+	mChan <- prometheus.MustNewConstMetric(suspendedUntilDesc, prometheus.GaugeValue, suspendedValue)
+	mChan <- prometheus.MustNewConstMetric(tokenRejectedDesc, prometheus.GaugeValue, tokenRejectedValue)
+}
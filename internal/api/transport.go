@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxRetries     = 3
+	backoffBase    = time.Second
+	backoffMax     = 30 * time.Second
+	maxRetryAfter  = time.Minute
+	authHeaderName = "Authorization"
+)
+
+// Transport is a http.RoundTripper for requests to the Netatmo API. It keeps the requests within the
+// rate limits of the API, retries failed requests with exponential backoff and stops sending requests
+// with an access token which has been rejected by the API.
+type Transport struct {
+	log     logrus.FieldLogger
+	base    http.RoundTripper
+	budgets []*Budget
+	clock   func() time.Time
+	wait    func(ctx context.Context, d time.Duration) error
+
+	lock             sync.Mutex
+	suspendedUntil   time.Time
+	rejectedToken    string
+	retries          int
+	rejectedRequests map[string]int
+}
+
+// NewTransport creates a new Transport. Requests are only sent if they fit into all of the budgets.
+func NewTransport(log logrus.FieldLogger, base http.RoundTripper, budgets ...*Budget) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		log:              log,
+		base:             base,
+		budgets:          budgets,
+		clock:            time.Now,
+		wait:             sleepContext,
+		rejectedRequests: map[string]int{},
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.checkSuspended(req); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.reserve(); err != nil {
+			return nil, err
+		}
+
+		try := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			try = req.Clone(req.Context())
+			try.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(try)
+		wait, retry := t.check(req, resp, err, attempt)
+		if !retry || attempt >= maxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		t.log.Debugf("Retrying request to %s in %s.", req.URL.Path, wait)
+		t.lock.Lock()
+		t.retries++
+		t.lock.Unlock()
+
+		if err := t.wait(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *Transport) checkSuspended(req *http.Request) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if auth := bearerToken(req); t.rejectedToken != "" && auth != "" {
+		if auth == t.rejectedToken {
+			t.rejectedRequests["token_invalid"]++
+			return ErrTokenInvalid
+		}
+
+		t.log.Info("New token available, resuming requests.")
+		t.rejectedToken = ""
+	}
+
+	if until := t.suspendedUntil; t.clock().Before(until) {
+		t.rejectedRequests["suspended"]++
+		return &SuspendedError{Until: until}
+	}
+
+	return nil
+}
+
+// reserve records the request in all budgets of the transport. If one of the budgets is exhausted, the
+// reservations already made in the other budgets are released again.
+func (t *Transport) reserve() error {
+	now := t.clock()
+	for i, b := range t.budgets {
+		available, ok := b.TryReserve(now)
+		if ok {
+			continue
+		}
+
+		for _, reserved := range t.budgets[:i] {
+			reserved.release(now)
+		}
+
+		t.lock.Lock()
+		t.rejectedRequests["budget"]++
+		t.lock.Unlock()
+
+		return &SuspendedError{Until: available}
+	}
+
+	return nil
+}
+
+// check decides if the request should be retried and how long to wait before the next try.
+func (t *Transport) check(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		return backoff(attempt), true
+	}
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return backoff(attempt), true
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden, resp.StatusCode == http.StatusTooManyRequests:
+	default:
+		return 0, false
+	}
+
+	apiErr := peekError(resp)
+	switch {
+	case apiErr.IsTokenError() && bearerToken(req) != "":
+		t.log.Warnf("Token rejected by API, suspending requests until a new token is available: %s", apiErr)
+
+		t.lock.Lock()
+		t.rejectedToken = bearerToken(req)
+		t.lock.Unlock()
+
+		return 0, false
+	case apiErr.IsRateLimit():
+		wait := backoff(attempt)
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), t.clock()); ok {
+			if retryAfter > maxRetryAfter {
+				until := t.clock().Add(retryAfter)
+				t.log.Warnf("Rate limit reached, suspending requests until %s.", until.Format(time.RFC3339))
+
+				t.lock.Lock()
+				t.suspendedUntil = until
+				t.lock.Unlock()
+
+				return 0, false
+			}
+
+			wait = max(wait, retryAfter)
+		}
+
+		return wait, true
+	default:
+		return 0, false
+	}
+}
+
+// bearerToken returns the access token used by the request, if there is one.
+func bearerToken(req *http.Request) string {
+	token, ok := strings.CutPrefix(req.Header.Get(authHeaderName), "Bearer ")
+	if !ok {
+		return ""
+	}
+
+	return token
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff returns the exponential backoff for the attempt with full jitter.
+func backoff(attempt int) time.Duration {
+	ceiling := min(backoffBase<<attempt, backoffMax)
+	return time.Duration(rand.Int64N(int64(ceiling))) + 1
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which can either be in seconds or a HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func testTransport(budgets ...*Budget) *Transport {
+	t := NewTransport(logrus.New(), nil, budgets...)
+	t.wait = func(context.Context, time.Duration) error { return nil }
+	return t
+}
+
+func doRequest(t *testing.T, client *http.Client, url, token string) (*http.Response, error) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("error creating request: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestTransportRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: testTransport()}
+	resp, err := doRequest(t, client, server.URL, "token")
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if got := calls.Load(); got != 3 {
+		t.Errorf("got %d calls, want 3", got)
+	}
+}
+
+func TestTransportRejectedToken(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("Authorization") == "Bearer old" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":{"code":3,"message":"Access token expired"}}`))
+		}
+	}))
+	defer server.Close()
+
+	transport := testTransport()
+	client := &http.Client{Transport: transport}
+
+	resp, err := doRequest(t, client, server.URL, "old")
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+
+	if _, err := doRequest(t, client, server.URL, "old"); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("got error %q, want %q", err, ErrTokenInvalid)
+	}
+
+	if _, err := doRequest(t, client, server.URL, "new"); err != nil {
+		t.Errorf("got error %q with new token", err)
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("got %d calls, want 2", got)
+	}
+}
+
+func TestTransportRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: testTransport()}
+	if _, err := doRequest(t, client, server.URL, "token"); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	var suspendedErr *SuspendedError
+	if _, err := doRequest(t, client, server.URL, "token"); !errors.As(err, &suspendedErr) {
+		t.Errorf("got error %q, want suspended error", err)
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("got %d calls, want 1", got)
+	}
+}
+
+func TestTransportBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	budget := NewBudget("test", []Limit{
+		{Name: "1h", Requests: 2, Window: time.Hour},
+	})
+	client := &http.Client{Transport: testTransport(budget)}
+
+	for i := 0; i < 2; i++ {
+		if _, err := doRequest(t, client, server.URL, "token"); err != nil {
+			t.Fatalf("got error %q", err)
+		}
+	}
+
+	var suspendedErr *SuspendedError
+	if _, err := doRequest(t, client, server.URL, "token"); !errors.As(err, &suspendedErr) {
+		t.Errorf("got error %q, want suspended error", err)
+	}
+
+	if remaining := budget.Remaining(time.Now()); remaining[0] != 0 {
+		t.Errorf("got %d remaining requests, want 0", remaining[0])
+	}
+}
+
+func TestTransportBudgetConcurrent(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	// Two accounts sharing the budget of one app
+	appBudget := NewBudget("app", []Limit{
+		{Name: "1h", Requests: 10, Window: time.Hour},
+	})
+	clients := []*http.Client{
+		{Transport: testTransport(NewBudget("user", UserLimits), appBudget)},
+		{Transport: testTransport(NewBudget("user", UserLimits), appBudget)},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(client *http.Client) {
+			defer wg.Done()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Errorf("error creating request: %s", err)
+				return
+			}
+
+			if resp, err := client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}(clients[i%len(clients)])
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 10 {
+		t.Errorf("got %d calls, want 10", got)
+	}
+}
+
+func TestTransportBudgetRelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	userBudget := NewBudget("user", []Limit{
+		{Name: "1h", Requests: 5, Window: time.Hour},
+	})
+	appBudget := NewBudget("app", []Limit{
+		{Name: "1h", Requests: 1, Window: time.Hour},
+	})
+	client := &http.Client{Transport: testTransport(userBudget, appBudget)}
+
+	if _, err := doRequest(t, client, server.URL, "token"); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	var suspendedErr *SuspendedError
+	if _, err := doRequest(t, client, server.URL, "token"); !errors.As(err, &suspendedErr) {
+		t.Errorf("got error %q, want suspended error", err)
+	}
+
+	// The request rejected by the app budget must not count towards the user budget.
+	if remaining := userBudget.Remaining(time.Now()); remaining[0] != 4 {
+		t.Errorf("got %d remaining requests, want 4", remaining[0])
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{value: "", want: 0, wantOk: false},
+		{value: "120", want: 2 * time.Minute, wantOk: true},
+		{value: "Wed, 01 Jan 2025 12:05:00 GMT", want: 5 * time.Minute, wantOk: true},
+		{value: "soon", want: 0, wantOk: false},
+	}
+
+	for _, tc := range tt {
+		got, ok := parseRetryAfter(tc.value, now)
+		if got != tc.want || ok != tc.wantOk {
+			t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tc.value, got, ok, tc.want, tc.wantOk)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+
+	"github.com/marc825/netatmo-exporter/v2/internal/api"
+	"github.com/marc825/netatmo-exporter/v2/internal/collector"
+)
+
+const (
+	// maxGap limits how far back measurements are backfilled.
+	maxGap = 7 * 24 * time.Hour
+
+	// accountLabel is the name of the label containing the account, if multiple accounts are used.
+	accountLabel = "account"
+)
+
+// backfillClasses contains the device classes which have measurements available using getmeasure.
+var backfillClasses = map[string]bool{
+	collector.DeviceClassWeather:   true,
+	collector.DeviceClassHomecoach: true,
+}
+
+// Backfiller detects gaps between successful refreshes of the store and fetches the measurements of
+// the gap using the getmeasure API. The time up to which the data is complete is persisted in a state
+// file, so that gaps caused by the exporter not running are detected as well. It is only advanced after
+// a gap has been backfilled successfully, so failed backfills are retried on the next refresh.
+type Backfiller struct {
+	log             logrus.FieldLogger
+	store           *collector.Store
+	getCurrentToken func() (*oauth2.Token, error)
+	apiClient       *http.Client
+	sink            Sink
+	minGap          time.Duration
+	favorites       bool
+	stateFile       string
+	account         string
+
+	stateLock sync.Mutex
+	state     map[string]time.Time
+	// running contains the time of the latest refresh of the device classes which are currently backfilled.
+	running map[string]time.Time
+
+	// runLock makes sure that only one backfill runs at a time.
+	runLock sync.Mutex
+}
+
+// New creates a new Backfiller. Gaps shorter than minGap are ignored. If favorites is set, the series get the
+// "owned" label of the sensor metrics. If the account is set, the series get an "account" label like the
+// metrics of the account.
+func New(log logrus.FieldLogger, store *collector.Store, getCurrentToken func() (*oauth2.Token, error), apiClient *http.Client, sink Sink, minGap time.Duration, favorites bool, stateFile, account string) (*Backfiller, error) {
+	state, err := loadState(stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backfiller{
+		log:             log,
+		store:           store,
+		getCurrentToken: getCurrentToken,
+		apiClient:       apiClient,
+		sink:            sink,
+		minGap:          minGap,
+		favorites:       favorites,
+		stateFile:       stateFile,
+		account:         account,
+		state:           state,
+		running:         make(map[string]time.Time),
+	}, nil
+}
+
+// OnRefresh is a collector.RefreshHook which starts a backfill in the background when a gap is detected.
+func (b *Backfiller) OnRefresh(name string, previous, current time.Time) {
+	if !backfillClasses[name] {
+		return
+	}
+
+	b.stateLock.Lock()
+	defer b.stateLock.Unlock()
+
+	if _, ok := b.running[name]; ok {
+		// The state is updated when the running backfill is done.
+		b.running[name] = current
+		return
+	}
+
+	last, ok := b.state[name]
+	if !ok {
+		last = previous
+	}
+
+	if last.IsZero() || current.Sub(last) < b.minGap {
+		b.state[name] = current
+		if err := saveState(b.stateFile, b.state); err != nil {
+			b.log.Errorf("Error saving backfill state: %s", err)
+		}
+		return
+	}
+
+	if current.Sub(last) > maxGap {
+		b.log.Warnf("Gap in %s data since %s is longer than %s, only backfilling the last %s.", name, last.Format(time.RFC3339), maxGap, maxGap)
+		last = current.Add(-maxGap)
+	}
+
+	b.running[name] = current
+	go b.backfill(name, last, current)
+}
+
+// backfill fills the gap between begin and end and advances the state to the latest refresh if successful.
+func (b *Backfiller) backfill(name string, begin, end time.Time) {
+	err := b.run(name, begin, end)
+
+	b.stateLock.Lock()
+	defer b.stateLock.Unlock()
+
+	latest := b.running[name]
+	delete(b.running, name)
+
+	if err != nil {
+		b.log.Errorf("Error during %s backfill, retrying on next refresh: %s", name, err)
+		return
+	}
+
+	if latest.Before(end) {
+		latest = end
+	}
+	b.state[name] = latest
+	if err := saveState(b.stateFile, b.state); err != nil {
+		b.log.Errorf("Error saving backfill state: %s", err)
+	}
+}
+
+// run fetches the measurements of all owned devices of the class between begin and end and writes them to
+// the sink.
+func (b *Backfiller) run(name string, begin, end time.Time) error {
+	b.runLock.Lock()
+	defer b.runLock.Unlock()
+
+	b.log.Infof("Backfilling %s data from %s to %s.", name, begin.Format(time.RFC3339), end.Format(time.RFC3339))
+
+	client, err := api.AuthenticatedClient(b.getCurrentToken, b.apiClient)
+	if err != nil {
+		return err
+	}
+
+	labelNames := collector.LabelNames()
+
+	var series []Series
+	for _, device := range b.store.Devices(name) {
+		// Measurements of favorite stations can not be read using getmeasure.
+		if !device.Owned {
+			continue
+		}
+
+		measurements, ok := measurementsByType[device.Type]
+		if !ok {
+			b.log.Debugf("No measurements known for device %s of type %q.", device.ID, device.Type)
+			continue
+		}
+
+		result, err := fetchMeasure(client, device.MainDeviceID, device.ID, measurements, begin, end)
+		if err != nil {
+			return fmt.Errorf("error fetching measurements of %s: %w", device.ID, err)
+		}
+
+		labels := make([]Label, len(labelNames), len(labelNames)+2)
+		for i, labelName := range labelNames {
+			labels[i] = Label{Name: labelName, Value: device.Labels[i]}
+		}
+		if b.favorites {
+			// Only owned devices are backfilled.
+			labels = append(labels, Label{Name: collector.OwnedLabel, Value: "true"})
+		}
+		if b.account != "" {
+			labels = append(labels, Label{Name: accountLabel, Value: b.account})
+		}
+
+		series = append(series, toSeries(measurements, labels, result, begin, end)...)
+	}
+
+	if len(series) == 0 {
+		b.log.Infof("No %s measurements found for backfill.", name)
+		return nil
+	}
+
+	backfillName := fmt.Sprintf("%s-%d-%d", name, begin.Unix(), end.Unix())
+	if b.account != "" {
+		backfillName = b.account + "-" + backfillName
+	}
+
+	if err := b.sink.Write(backfillName, series); err != nil {
+		return fmt.Errorf("error writing backfill: %w", err)
+	}
+
+	b.log.Infof("Backfilled %d %s series.", len(series), name)
+	return nil
+}
+
+// toSeries creates one series per measurement, containing the samples strictly between begin and end.
+func toSeries(measurements []measurement, labels []Label, result measureResult, begin, end time.Time) []Series {
+	series := make([]Series, len(measurements))
+	for i, m := range measurements {
+		series[i] = Series{
+			Metric: m.Metric,
+			Help:   m.Help,
+			Labels: labels,
+		}
+	}
+
+	for _, ts := range result.sortedTimes() {
+		if !ts.After(begin) || !ts.Before(end) {
+			continue
+		}
+
+		for i, value := range result[ts] {
+			if i >= len(series) || value == nil {
+				continue
+			}
+
+			series[i].Samples = append(series[i].Samples, Sample{
+				Time:  ts,
+				Value: *value,
+			})
+		}
+	}
+
+	nonEmpty := series[:0]
+	for _, s := range series {
+		if len(s.Samples) > 0 {
+			nonEmpty = append(nonEmpty, s)
+		}
+	}
+
+	return nonEmpty
+}
+
+func loadState(fileName string) (map[string]time.Time, error) {
+	state := map[string]time.Time{}
+
+	data, err := os.ReadFile(fileName)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return state, nil
+	case err != nil:
+		return nil, fmt.Errorf("error reading backfill state: %w", err)
+	default:
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error decoding backfill state: %w", err)
+	}
+
+	return state, nil
+}
+
+func saveState(fileName string, state map[string]time.Time) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error marshalling backfill state: %w", err)
+	}
+
+	if err := os.WriteFile(fileName, data, 0o600); err != nil {
+		return fmt.Errorf("error writing backfill state: %w", err)
+	}
+
+	return nil
+}
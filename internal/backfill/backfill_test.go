@@ -0,0 +1,317 @@
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+
+	"github.com/marc825/netatmo-exporter/v2/internal/collector"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestToSeries(t *testing.T) {
+	labels := []Label{{Name: "device_id", Value: "id"}}
+	begin := time.Unix(1000, 0)
+	end := time.Unix(2000, 0)
+
+	tests := []struct {
+		desc   string
+		result measureResult
+		want   []Series
+	}{
+		{
+			desc:   "empty",
+			result: measureResult{},
+			want:   []Series{},
+		},
+		{
+			desc: "skips boundaries and null values",
+			result: measureResult{
+				time.Unix(1000, 0): {float(20), float(40)},
+				time.Unix(1600, 0): {float(22), nil},
+				time.Unix(1300, 0): {float(21), float(41)},
+				time.Unix(2000, 0): {float(23), float(43)},
+			},
+			want: []Series{
+				{
+					Metric: temperature.Metric,
+					Help:   temperature.Help,
+					Labels: labels,
+					Samples: []Sample{
+						{Time: time.Unix(1300, 0), Value: 21},
+						{Time: time.Unix(1600, 0), Value: 22},
+					},
+				},
+				{
+					Metric: humidity.Metric,
+					Help:   humidity.Help,
+					Labels: labels,
+					Samples: []Sample{
+						{Time: time.Unix(1300, 0), Value: 41},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := toSeries([]measurement{temperature, humidity}, labels, tc.result, begin, end)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("series differ: -want +got\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestState(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := loadState(fileName)
+	if err != nil {
+		t.Fatalf("error loading missing state: %s", err)
+	}
+	if len(state) != 0 {
+		t.Fatalf("got state %v, want empty", state)
+	}
+
+	want := map[string]time.Time{
+		"weather": time.Unix(1000, 0).UTC(),
+	}
+	if err := saveState(fileName, want); err != nil {
+		t.Fatalf("error saving state: %s", err)
+	}
+
+	got, err := loadState(fileName)
+	if err != nil {
+		t.Fatalf("error loading state: %s", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("state differs: -want +got\n%s", diff)
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type recordingSink struct {
+	series []Series
+}
+
+func (s *recordingSink) Write(_ string, series []Series) error {
+	s.series = append(s.series, series...)
+	return nil
+}
+
+const testWeatherJSON = `{
+	"body": {
+		"devices": [
+			{
+				"_id": "70:ee:50:00:00:01",
+				"type": "NAModule1",
+				"home_name": "Home",
+				"station_name": "Home",
+				"module_name": "Outdoor"
+			},
+			{
+				"_id": "70:ee:50:00:00:02",
+				"type": "NAModule1",
+				"home_name": "Neighbour",
+				"station_name": "Neighbour",
+				"module_name": "Garden",
+				"read_only": true
+			}
+		]
+	}
+}`
+
+func newTestStore(t *testing.T, log logrus.FieldLogger) *collector.Store {
+	t.Helper()
+
+	var weather collector.WeatherResponse
+	if err := json.Unmarshal([]byte(testWeatherJSON), &weather); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	store := collector.NewStore(log, func() (*collector.WeatherResponse, error) {
+		return &weather, nil
+	}, nil, nil, time.Hour)
+	store.RefreshWeather()
+
+	return store
+}
+
+// measureClient returns an API client which answers every getmeasure request with the body.
+func measureClient(body string) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})}
+}
+
+func testToken() (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: "token"}, nil
+}
+
+func TestBackfillFavorites(t *testing.T) {
+	log := logrus.New()
+	store := newTestStore(t, log)
+
+	var requests int
+	apiClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"body": {"1500": [21.5, 60]}}`)),
+			Request:    req,
+		}, nil
+	})}
+
+	sink := &recordingSink{}
+	b, err := New(log, store, testToken, apiClient, sink, time.Minute, true, filepath.Join(t.TempDir(), "state.json"), "")
+	if err != nil {
+		t.Fatalf("error creating backfill: %s", err)
+	}
+
+	b.backfill(collector.DeviceClassWeather, time.Unix(1000, 0), time.Unix(2000, 0))
+
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+
+	labels := []Label{
+		{Name: "device_class", Value: "weather"},
+		{Name: "device_id", Value: "70:ee:50:00:00:01"},
+		{Name: "home", Value: "Home"},
+		{Name: "module", Value: "Outdoor"},
+		{Name: "station", Value: "Home"},
+		{Name: "owned", Value: "true"},
+	}
+	want := []Series{
+		{
+			Metric:  temperature.Metric,
+			Help:    temperature.Help,
+			Labels:  labels,
+			Samples: []Sample{{Time: time.Unix(1500, 0), Value: 21.5}},
+		},
+		{
+			Metric:  humidity.Metric,
+			Help:    humidity.Help,
+			Labels:  labels,
+			Samples: []Sample{{Time: time.Unix(1500, 0), Value: 60}},
+		},
+	}
+
+	if diff := cmp.Diff(want, sink.series); diff != "" {
+		t.Errorf("series differ: -want +got\n%s", diff)
+	}
+}
+
+// failingSink records the names of the written backfills and fails while fail is set.
+type failingSink struct {
+	fail  atomic.Bool
+	names chan string
+}
+
+func (s *failingSink) Write(name string, _ []Series) error {
+	s.names <- name
+	if s.fail.Load() {
+		return errors.New("write failed")
+	}
+
+	return nil
+}
+
+// waitDone waits until no backfill is running anymore.
+func waitDone(t *testing.T, b *Backfiller) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		b.stateLock.Lock()
+		running := len(b.running)
+		b.stateLock.Unlock()
+
+		if running == 0 {
+			return
+		}
+	}
+
+	t.Fatal("backfill did not finish")
+}
+
+func TestBackfillRetry(t *testing.T) {
+	log := logrus.New()
+	store := newTestStore(t, log)
+
+	start := time.Unix(1700000000, 0).UTC()
+	apiClient := measureClient(fmt.Sprintf(`{"body": {"%d": [21.5, 60]}}`, start.Add(30*time.Minute).Unix()))
+
+	sink := &failingSink{names: make(chan string, 2)}
+	sink.fail.Store(true)
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	b, err := New(log, store, testToken, apiClient, sink, time.Minute, false, stateFile, "")
+	if err != nil {
+		t.Fatalf("error creating backfill: %s", err)
+	}
+
+	// The first refresh sets the start of the complete data.
+	b.OnRefresh(collector.DeviceClassWeather, time.Time{}, start)
+
+	// The backfill of the gap fails, so the state is not advanced.
+	b.OnRefresh(collector.DeviceClassWeather, start, start.Add(time.Hour))
+	<-sink.names
+	waitDone(t, b)
+
+	state, err := loadState(stateFile)
+	if err != nil {
+		t.Fatalf("error loading state: %s", err)
+	}
+	if got := state[collector.DeviceClassWeather]; !got.Equal(start) {
+		t.Errorf("got state %s after failed backfill, want %s", got, start)
+	}
+
+	// The next refresh retries the backfill from the same start.
+	sink.fail.Store(false)
+	end := start.Add(2 * time.Hour)
+	b.OnRefresh(collector.DeviceClassWeather, start.Add(time.Hour), end)
+
+	wantName := fmt.Sprintf("weather-%d-%d", start.Unix(), end.Unix())
+	if got := <-sink.names; got != wantName {
+		t.Errorf("got backfill %q, want %q", got, wantName)
+	}
+	waitDone(t, b)
+
+	state, err = loadState(stateFile)
+	if err != nil {
+		t.Fatalf("error loading state: %s", err)
+	}
+	if got := state[collector.DeviceClassWeather]; !got.Equal(end) {
+		t.Errorf("got state %s after backfill, want %s", got, end)
+	}
+}
@@ -0,0 +1,147 @@
+package backfill
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marc825/netatmo-exporter/v2/internal/api"
+)
+
+const (
+	measureURL = "https://api.netatmo.com/api/getmeasure"
+
+	// maxMeasurements is the maximum number of measurements returned by a single getmeasure request.
+	maxMeasurements = 1024
+)
+
+// measurement maps a getmeasure type to the V2 metric it is exported as.
+type measurement struct {
+	Type   string
+	Metric string
+	Help   string
+}
+
+var (
+	temperature   = measurement{Type: "temperature", Metric: "netatmo_sensor_temperature_celsius", Help: "Temperature measurement in celsius"}
+	humidity      = measurement{Type: "humidity", Metric: "netatmo_sensor_humidity_percent", Help: "Relative humidity measurement in percent"}
+	co2           = measurement{Type: "co2", Metric: "netatmo_sensor_co2_ppm", Help: "Carbondioxide measurement in parts per million"}
+	noise         = measurement{Type: "noise", Metric: "netatmo_sensor_noise_db", Help: "Noise measurement in decibels"}
+	pressure      = measurement{Type: "pressure", Metric: "netatmo_sensor_pressure_mb", Help: "Atmospheric pressure measurement in millibar"}
+	rain          = measurement{Type: "rain", Metric: "netatmo_sensor_rain_amount_mm", Help: "Rain amount in millimeters"}
+	windStrength  = measurement{Type: "windstrength", Metric: "netatmo_sensor_wind_strength_kph", Help: "Wind strength in kilometers per hour"}
+	windDirection = measurement{Type: "windangle", Metric: "netatmo_sensor_wind_direction_degrees", Help: "Wind direction in degrees"}
+
+	// measurementsByType contains the measurements available for every device type.
+	measurementsByType = map[string][]measurement{
+		"NAMain":    {temperature, humidity, co2, pressure, noise},
+		"NAModule1": {temperature, humidity},
+		"NAModule2": {windStrength, windDirection},
+		"NAModule3": {rain},
+		"NAModule4": {temperature, humidity, co2},
+		"NHC":       {temperature, humidity, co2, pressure, noise},
+	}
+)
+
+// measureResult contains the values of one getmeasure request by timestamp.
+// The values are in the order of the requested types and can be null.
+type measureResult map[time.Time][]*float64
+
+// fetchMeasure reads all measurements of a device between begin and end, requesting multiple pages if necessary.
+func fetchMeasure(client *http.Client, deviceID, moduleID string, measurements []measurement, begin, end time.Time) (measureResult, error) {
+	result := measureResult{}
+	for begin.Before(end) {
+		page, err := fetchMeasurePage(client, deviceID, moduleID, measurements, begin, end)
+		if err != nil {
+			return nil, err
+		}
+
+		var last time.Time
+		for ts, values := range page {
+			result[ts] = values
+			if ts.After(last) {
+				last = ts
+			}
+		}
+
+		if len(page) < maxMeasurements {
+			break
+		}
+		begin = last.Add(time.Second)
+	}
+
+	return result, nil
+}
+
+func fetchMeasurePage(client *http.Client, deviceID, moduleID string, measurements []measurement, begin, end time.Time) (measureResult, error) {
+	types := make([]string, 0, len(measurements))
+	for _, m := range measurements {
+		types = append(types, m.Type)
+	}
+
+	query := url.Values{}
+	query.Set("device_id", deviceID)
+	if moduleID != "" && moduleID != deviceID {
+		query.Set("module_id", moduleID)
+	}
+	query.Set("scale", "max")
+	query.Set("type", strings.Join(types, ","))
+	query.Set("date_begin", strconv.FormatInt(begin.Unix(), 10))
+	query.Set("date_end", strconv.FormatInt(end.Unix(), 10))
+	query.Set("limit", strconv.Itoa(maxMeasurements))
+	query.Set("optimize", "false")
+	query.Set("real_time", "true")
+
+	req, err := http.NewRequest(http.MethodGet, measureURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating getmeasure request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing getmeasure request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getmeasure request failed: %w", api.ResponseError(resp))
+	}
+
+	var body struct {
+		Body map[string][]*float64 `json:"body"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		api.ReportDecodeError(resp)
+		return nil, fmt.Errorf("decoding getmeasure response: %w", err)
+	}
+
+	result := make(measureResult, len(body.Body))
+	for key, values := range body.Body {
+		ts, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp in getmeasure response: %q", key)
+		}
+
+		result[time.Unix(ts, 0)] = values
+	}
+
+	return result, nil
+}
+
+// sortedTimes returns the timestamps of the result in ascending order.
+func (r measureResult) sortedTimes() []time.Time {
+	times := make([]time.Time, 0, len(r))
+	for ts := range r {
+		times = append(times, ts)
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+
+	return times
+}
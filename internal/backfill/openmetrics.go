@@ -0,0 +1,94 @@
+package backfill
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenMetricsSink writes every backfill to a file in the OpenMetrics text format, which can be imported
+// using "promtool tsdb create-blocks-from openmetrics".
+type OpenMetricsSink struct {
+	directory string
+}
+
+// NewOpenMetricsSink creates a sink which writes the files into the directory.
+func NewOpenMetricsSink(directory string) *OpenMetricsSink {
+	return &OpenMetricsSink{
+		directory: directory,
+	}
+}
+
+// Write implements Sink
+func (s *OpenMetricsSink) Write(name string, series []Series) error {
+	fileName := filepath.Join(s.directory, "netatmo-backfill-"+name+".om")
+	tempName := fileName + ".tmp"
+
+	file, err := os.Create(tempName)
+	if err != nil {
+		return fmt.Errorf("error creating backfill file: %w", err)
+	}
+	defer os.Remove(tempName)
+
+	w := bufio.NewWriter(file)
+	writeOpenMetrics(w, series)
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing backfill file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error closing backfill file: %w", err)
+	}
+
+	if err := os.Rename(tempName, fileName); err != nil {
+		return fmt.Errorf("error renaming backfill file: %w", err)
+	}
+
+	return nil
+}
+
+// writeOpenMetrics writes the series grouped by metric, as the samples of a metric family may not be interleaved.
+func writeOpenMetrics(w *bufio.Writer, series []Series) {
+	sorted := make([]Series, len(series))
+	copy(sorted, series)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Metric < sorted[j].Metric
+	})
+
+	currentMetric := ""
+	for _, s := range sorted {
+		if s.Metric != currentMetric {
+			fmt.Fprintf(w, "# HELP %s %s\n", s.Metric, escaper.Replace(s.Help))
+			fmt.Fprintf(w, "# TYPE %s gauge\n", s.Metric)
+			currentMetric = s.Metric
+		}
+
+		labels := formatLabels(s.Labels)
+		for _, sample := range s.Samples {
+			fmt.Fprintf(w, "%s%s %s %d\n", s.Metric, labels, strconv.FormatFloat(sample.Value, 'g', -1, 64), sample.Time.Unix())
+		}
+	}
+
+	fmt.Fprint(w, "# EOF\n")
+}
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(labels))
+	for _, l := range labels {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", l.Name, escaper.Replace(l.Value)))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// escaper escapes label values and help texts as defined by OpenMetrics.
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
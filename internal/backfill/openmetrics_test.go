@@ -0,0 +1,57 @@
+package backfill
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	labels := []Label{
+		{Name: "device_class", Value: "weather"},
+		{Name: "module", Value: "Outdoor \"North\""},
+	}
+
+	series := []Series{
+		{
+			Metric: "netatmo_sensor_temperature_celsius",
+			Help:   "Temperature measurement in celsius",
+			Labels: labels,
+			Samples: []Sample{
+				{Time: time.Unix(1000, 0), Value: 21.5},
+				{Time: time.Unix(1300, 0), Value: 21.7},
+			},
+		},
+		{
+			Metric: "netatmo_sensor_humidity_percent",
+			Help:   "Relative humidity measurement in percent",
+			Labels: labels,
+			Samples: []Sample{
+				{Time: time.Unix(1000, 0), Value: 45},
+			},
+		},
+	}
+
+	var sb strings.Builder
+	w := bufio.NewWriter(&sb)
+	writeOpenMetrics(w, series)
+	if err := w.Flush(); err != nil {
+		t.Fatalf("error flushing: %s", err)
+	}
+
+	want := `# HELP netatmo_sensor_humidity_percent Relative humidity measurement in percent
+# TYPE netatmo_sensor_humidity_percent gauge
+netatmo_sensor_humidity_percent{device_class="weather",module="Outdoor \"North\""} 45 1000
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{device_class="weather",module="Outdoor \"North\""} 21.5 1000
+netatmo_sensor_temperature_celsius{device_class="weather",module="Outdoor \"North\""} 21.7 1300
+# EOF
+`
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("output differs: -want +got\n%s", diff)
+	}
+}
@@ -0,0 +1,103 @@
+package backfill
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const remoteWriteTimeout = 30 * time.Second
+
+// RemoteWriteSink sends the backfilled samples to a Prometheus remote-write endpoint.
+// The receiver needs to accept out-of-order samples for backfilling to work.
+type RemoteWriteSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewRemoteWriteSink creates a sink which sends the samples to the remote-write URL.
+func NewRemoteWriteSink(url string) *RemoteWriteSink {
+	return &RemoteWriteSink{
+		url: url,
+		client: &http.Client{
+			Timeout: remoteWriteTimeout,
+		},
+	}
+}
+
+// Write implements Sink
+func (s *RemoteWriteSink) Write(_ string, series []Series) error {
+	body := snappy.Encode(nil, encodeWriteRequest(series))
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating remote-write request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending remote-write request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote-write request failed: status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// encodeWriteRequest encodes the series as a remote-write protobuf WriteRequest message.
+func encodeWriteRequest(series []Series) []byte {
+	var b []byte
+	for _, s := range series {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, encodeTimeSeries(s))
+	}
+
+	return b
+}
+
+func encodeTimeSeries(s Series) []byte {
+	// labels need to be sorted by name, including the metric name
+	labels := append([]Label{{Name: "__name__", Value: s.Metric}}, s.Labels...)
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+
+	var b []byte
+	for _, l := range labels {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, l.Name)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, l.Value)
+
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, label)
+	}
+
+	for _, sample := range s.Samples {
+		var encoded []byte
+		encoded = protowire.AppendTag(encoded, 1, protowire.Fixed64Type)
+		encoded = protowire.AppendFixed64(encoded, math.Float64bits(sample.Value))
+		encoded = protowire.AppendTag(encoded, 2, protowire.VarintType)
+		encoded = protowire.AppendVarint(encoded, uint64(sample.Time.UnixMilli()))
+
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, encoded)
+	}
+
+	return b
+}
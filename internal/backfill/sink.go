@@ -0,0 +1,31 @@
+package backfill
+
+import (
+	"time"
+)
+
+// Label is a single label of a series.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a single value of a series at a point in time.
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series contains the samples of a single time series which should be backfilled.
+type Series struct {
+	Metric  string
+	Help    string
+	Labels  []Label
+	Samples []Sample
+}
+
+// Sink is the destination of backfilled samples.
+type Sink interface {
+	// Write writes the series of one backfill. The name identifies the backfill and is unique per endpoint and gap.
+	Write(name string, series []Series) error
+}
@@ -0,0 +1,141 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// batteryHistoryWindow is the time range of voltage samples used for estimating the remaining battery life.
+	batteryHistoryWindow = 7 * 24 * time.Hour
+	// batteryMinHistory is the minimum time range of voltage samples needed for an estimation.
+	batteryMinHistory = 24 * time.Hour
+)
+
+// batteryStates contains the battery states derived from the voltage, from full to very low.
+var batteryStates = []string{"full", "high", "medium", "low", "very_low"}
+
+// batteryThresholds contains the minimum voltage in millivolts of the states full, high, medium and low
+// by module type. Voltages below the last threshold are very low.
+var batteryThresholds = map[string][4]int{
+	typeWeatherOutdoor: {5500, 5000, 4500, 4000},
+	typeWeatherWind:    {5590, 5180, 4770, 4360},
+	typeWeatherRain:    {5500, 5000, 4500, 4000},
+	typeWeatherIndoor:  {5640, 5280, 4920, 4560},
+}
+
+// batteryState returns the state of a battery with the given voltage in millivolts. It returns false for
+// module types without known thresholds.
+func batteryState(moduleType string, voltage int) (string, bool) {
+	thresholds, ok := batteryThresholds[moduleType]
+	if !ok {
+		return "", false
+	}
+
+	for i, threshold := range thresholds {
+		if voltage >= threshold {
+			return batteryStates[i], true
+		}
+	}
+
+	return batteryStates[len(batteryStates)-1], true
+}
+
+// batterySample is a battery voltage in millivolts read at a specific time.
+type batterySample struct {
+	time    time.Time
+	voltage int
+}
+
+// batteryHistory keeps the recent battery voltages of every module.
+type batteryHistory struct {
+	lock    sync.Mutex
+	samples map[string][]batterySample
+}
+
+func newBatteryHistory() *batteryHistory {
+	return &batteryHistory{
+		samples: make(map[string][]batterySample),
+	}
+}
+
+// add stores a voltage sample of a module and removes the samples which are outside of the history window.
+func (h *batteryHistory) add(moduleID string, t time.Time, voltage int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	samples := h.samples[moduleID]
+	if len(samples) > 0 && !t.After(samples[len(samples)-1].time) {
+		return
+	}
+
+	samples = append(samples, batterySample{time: t, voltage: voltage})
+
+	start := 0
+	for start < len(samples) && t.Sub(samples[start].time) > batteryHistoryWindow {
+		start++
+	}
+
+	h.samples[moduleID] = samples[start:]
+}
+
+// daysToEmpty estimates the days until the battery voltage of a module drops below the low threshold of its
+// type, using the slope of a linear regression over the voltage history. It returns false if the history is
+// too short or the voltage is not dropping.
+func (h *batteryHistory) daysToEmpty(moduleID, moduleType string) (float64, bool) {
+	thresholds, ok := batteryThresholds[moduleType]
+	if !ok {
+		return 0, false
+	}
+	empty := float64(thresholds[len(thresholds)-1])
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	samples := h.samples[moduleID]
+	if len(samples) < 2 {
+		return 0, false
+	}
+
+	first := samples[0].time
+	last := samples[len(samples)-1]
+	if last.time.Sub(first) < batteryMinHistory {
+		return 0, false
+	}
+
+	slope := batterySlope(samples)
+	if slope >= 0 {
+		return 0, false
+	}
+
+	remaining := float64(last.voltage) - empty
+	if remaining <= 0 {
+		return 0, true
+	}
+
+	return remaining / -slope, true
+}
+
+// batterySlope returns the slope of the linear regression of the samples in millivolts per day.
+func batterySlope(samples []batterySample) float64 {
+	first := samples[0].time
+	n := float64(len(samples))
+
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.time.Sub(first).Hours() / 24
+		y := float64(s.voltage)
+
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+
+	return (n*sumXY - sumX*sumY) / denominator
+}
@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatteryState(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		moduleType string
+		voltage    int
+		wantState  string
+		wantOK     bool
+	}{
+		{moduleType: typeWeatherOutdoor, voltage: 6000, wantState: "full", wantOK: true},
+		{moduleType: typeWeatherOutdoor, voltage: 5000, wantState: "high", wantOK: true},
+		{moduleType: typeWeatherOutdoor, voltage: 4999, wantState: "medium", wantOK: true},
+		{moduleType: typeWeatherOutdoor, voltage: 3999, wantState: "very_low", wantOK: true},
+		{moduleType: typeWeatherWind, voltage: 4400, wantState: "low", wantOK: true},
+		{moduleType: typeWeatherRain, voltage: 4600, wantState: "medium", wantOK: true},
+		{moduleType: typeWeatherIndoor, voltage: 5500, wantState: "high", wantOK: true},
+		{moduleType: typeWeatherMain, voltage: 5500, wantOK: false},
+	}
+
+	for _, tc := range tests {
+		state, ok := batteryState(tc.moduleType, tc.voltage)
+		if state != tc.wantState || ok != tc.wantOK {
+			t.Errorf("batteryState(%s, %d) = (%q, %v), want (%q, %v)", tc.moduleType, tc.voltage, state, ok, tc.wantState, tc.wantOK)
+		}
+	}
+}
+
+func TestBatteryDaysToEmpty(t *testing.T) {
+	t.Parallel()
+
+	start := time.Unix(1700000000, 0)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		desc     string
+		voltages []int
+		interval time.Duration
+		wantDays float64
+		wantOK   bool
+	}{
+		{
+			desc:     "too short",
+			voltages: []int{5000, 4990, 4980},
+			interval: time.Hour,
+		},
+		{
+			desc:     "dropping",
+			voltages: []int{5030, 5020, 5010, 5000},
+			interval: day,
+			wantDays: 100,
+			wantOK:   true,
+		},
+		{
+			desc:     "constant",
+			voltages: []int{5000, 5000, 5000},
+			interval: day,
+		},
+		{
+			desc:     "already empty",
+			voltages: []int{4020, 4000, 3980},
+			interval: day,
+			wantDays: 0,
+			wantOK:   true,
+		},
+		{
+			desc:     "outside of window",
+			voltages: []int{5900, 5800, 5070, 5060, 5050, 5040, 5030, 5020, 5010, 5000},
+			interval: day,
+			wantDays: 100,
+			wantOK:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			history := newBatteryHistory()
+			for i, voltage := range tc.voltages {
+				history.add("module", start.Add(time.Duration(i)*tc.interval), voltage)
+			}
+
+			days, ok := history.daysToEmpty("module", typeWeatherOutdoor)
+			if ok != tc.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tc.wantOK)
+			}
+			if diff := days - tc.wantDays; diff > 1e-6 || diff < -1e-6 {
+				t.Errorf("got %f days, want %f", days, tc.wantDays)
+			}
+		})
+	}
+}
+
+func TestUnifiedCollectorBattery(t *testing.T) {
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(testWeatherJSON), &weather); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	now := testWeatherTime
+	log := logrus.New()
+	store := NewStore(log, func() (*WeatherResponse, error) {
+		// The voltage of the outdoor module drops by 10 mV per day.
+		voltage := 5250 - int(now.Sub(testWeatherTime).Hours()/24)*10
+		details := weather.Details["02:00:00:00:00:01"]
+		details.BatteryVP = &voltage
+		weather.Details["02:00:00:00:00:01"] = details
+
+		return &weather, nil
+	}, nil, nil, time.Hour)
+	store.clock = func() time.Time {
+		return now
+	}
+
+	collector := UnifiedCollector(log, store, time.Hour, StaleKeep, false, UnitsMetric)
+	store.OnRefresh(collector.OnRefresh)
+
+	for i := 0; i < 3; i++ {
+		now = testWeatherTime.Add(time.Duration(i) * 24 * time.Hour)
+		store.RefreshWeather()
+	}
+
+	want := `# HELP netatmo_sensor_battery_days_to_empty Estimated days until the battery state is very low, based on the voltage drop of the last days
+# TYPE netatmo_sensor_battery_days_to_empty gauge
+netatmo_sensor_battery_days_to_empty{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 123
+# HELP netatmo_sensor_battery_state Battery state derived from the voltage and the module type
+# TYPE netatmo_sensor_battery_state gauge
+netatmo_sensor_battery_state{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",state="full",station="Home (Indoor)"} 0
+netatmo_sensor_battery_state{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",state="high",station="Home (Indoor)"} 1
+netatmo_sensor_battery_state{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",state="low",station="Home (Indoor)"} 0
+netatmo_sensor_battery_state{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",state="medium",station="Home (Indoor)"} 0
+netatmo_sensor_battery_state{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",state="very_low",station="Home (Indoor)"} 0
+netatmo_sensor_battery_state{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",state="full",station="Home (Indoor)"} 0
+netatmo_sensor_battery_state{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",state="high",station="Home (Indoor)"} 0
+netatmo_sensor_battery_state{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",state="low",station="Home (Indoor)"} 1
+netatmo_sensor_battery_state{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",state="medium",station="Home (Indoor)"} 0
+netatmo_sensor_battery_state{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",state="very_low",station="Home (Indoor)"} 0
+# HELP netatmo_sensor_battery_volts Battery voltage in volts
+# TYPE netatmo_sensor_battery_volts gauge
+netatmo_sensor_battery_volts{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 5.23
+netatmo_sensor_battery_volts{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",station="Home (Indoor)"} 4.4
+`
+
+	metricNames := []string{
+		"netatmo_sensor_battery_days_to_empty",
+		"netatmo_sensor_battery_state",
+		"netatmo_sensor_battery_volts",
+	}
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,7 +1,7 @@
 package collector
 
 import (
-	"sync"
+	"strconv"
 	"time"
 
 	netatmo "github.com/exzz/netatmo-api-go"
@@ -17,23 +17,186 @@ var (
 // V2 unified label names
 var v2LabelNames = []string{"device_class", "device_id", "home", "module", "station"}
 
-// V2 unified metric descriptors
-var (
-	// Sensor data metrics
-	v2UpdatedDesc       = prometheus.NewDesc(sensorPrefix+"updated", "Timestamp of last update", v2LabelNames, nil)
-	v2TempDesc          = prometheus.NewDesc(sensorPrefix+"temperature_celsius", "Temperature measurement in celsius", v2LabelNames, nil)
-	v2HumidityDesc      = prometheus.NewDesc(sensorPrefix+"humidity_percent", "Relative humidity measurement in percent", v2LabelNames, nil)
-	v2CO2Desc           = prometheus.NewDesc(sensorPrefix+"co2_ppm", "Carbondioxide measurement in parts per million", v2LabelNames, nil)
-	v2NoiseDesc         = prometheus.NewDesc(sensorPrefix+"noise_db", "Noise measurement in decibels", v2LabelNames, nil)
-	v2PressureDesc      = prometheus.NewDesc(sensorPrefix+"pressure_mb", "Atmospheric pressure measurement in millibar", v2LabelNames, nil)
-	v2RainDesc          = prometheus.NewDesc(sensorPrefix+"rain_amount_mm", "Rain amount in millimeters", v2LabelNames, nil)
-	v2WindStrengthDesc  = prometheus.NewDesc(sensorPrefix+"wind_strength_kph", "Wind strength in kilometers per hour", v2LabelNames, nil)
-	v2WindDirectionDesc = prometheus.NewDesc(sensorPrefix+"wind_direction_degrees", "Wind direction in degrees", v2LabelNames, nil)
-	v2BatteryDesc       = prometheus.NewDesc(sensorPrefix+"battery_percent", "Battery remaining life (10: low)", v2LabelNames, nil)
-	v2WifiDesc          = prometheus.NewDesc(sensorPrefix+"wifi_signal_strength", "Wifi signal strength (86: bad, 71: avg, 56: good)", v2LabelNames, nil)
-	v2RFDesc            = prometheus.NewDesc(sensorPrefix+"rf_signal_strength", "RF signal strength (90: lowest, 60: highest)", v2LabelNames, nil)
-	v2HealthIndexDesc   = prometheus.NewDesc(sensorPrefix+"health_index", "Air quality health index (0: Healthy, 1: Fine, 2: Fair, 3: Poor, 4: Unhealthy)", v2LabelNames, nil)
+// StalePolicy defines how devices are handled whose latest measurement is older than the stale threshold.
+type StalePolicy string
+
+const (
+	// StaleDrop stops exporting the measurements of stale devices.
+	StaleDrop StalePolicy = "drop"
+	// StaleKeep keeps exporting the last measurements of stale devices.
+	StaleKeep StalePolicy = "keep"
+	// StaleMark keeps exporting the last measurements and adds a marker metric, which is one for stale devices.
+	StaleMark StalePolicy = "mark"
+)
+
+// OwnedLabel is added to the V2 sensor metrics if favorite stations are included.
+const OwnedLabel = "owned"
+
+// trendStates contains the known values of temp_trend and pressure_trend.
+var trendStates = []string{"up", "down", "stable"}
+
+// v2SensorDescs contains the descriptors of the V2 sensor metrics. Their label names depend on whether
+// favorite stations are included.
+type v2SensorDescs struct {
+	updated       *prometheus.Desc
+	temp          *prometheus.Desc
+	humidity      *prometheus.Desc
+	co2           *prometheus.Desc
+	noise         *prometheus.Desc
+	pressure      *prometheus.Desc
+	rain          *prometheus.Desc
+	windStrength  *prometheus.Desc
+	windDirection *prometheus.Desc
+	battery       *prometheus.Desc
+	batteryVolts  *prometheus.Desc
+	batteryState  *prometheus.Desc
+	batteryEmpty  *prometheus.Desc
+	wifi          *prometheus.Desc
+	rf            *prometheus.Desc
+	healthIndex   *prometheus.Desc
+
+	minTemp          *prometheus.Desc
+	maxTemp          *prometheus.Desc
+	minTempTime      *prometheus.Desc
+	maxTempTime      *prometheus.Desc
+	absolutePressure *prometheus.Desc
+
+	rain1h              *prometheus.Desc
+	rain24h             *prometheus.Desc
+	gustStrength        *prometheus.Desc
+	gustDirection       *prometheus.Desc
+	maxWindStrength     *prometheus.Desc
+	maxWindStrengthTime *prometheus.Desc
+	tempTrend           *prometheus.Desc
+	pressureTrend       *prometheus.Desc
+
+	info     *prometheus.Desc
+	location *prometheus.Desc
+
+	reachable       *prometheus.Desc
+	lastStatusStore *prometheus.Desc
+	lastSeen        *prometheus.Desc
+	lastMessage     *prometheus.Desc
+	dataAge         *prometheus.Desc
+	stale           *prometheus.Desc
+
+	tempFahrenheit  *prometheus.Desc
+	pressureInHg    *prometheus.Desc
+	windStrengthMPH *prometheus.Desc
+	gustStrengthMPH *prometheus.Desc
+	rainInches      *prometheus.Desc
+}
+
+func newV2SensorDescs(labelNames []string) v2SensorDescs {
+	stateLabelNames := withLabels(labelNames, "state")
+	infoLabelNames := withLabels(labelNames, "type", "firmware", "date_setup", "last_upgrade", "city", "country", "timezone", "altitude")
+	locationLabelNames := withLabels(labelNames, "latitude", "longitude")
+
+	return v2SensorDescs{
+		updated:       prometheus.NewDesc(sensorPrefix+"updated", "Timestamp of last update", labelNames, nil),
+		temp:          prometheus.NewDesc(sensorPrefix+"temperature_celsius", "Temperature measurement in celsius", labelNames, nil),
+		humidity:      prometheus.NewDesc(sensorPrefix+"humidity_percent", "Relative humidity measurement in percent", labelNames, nil),
+		co2:           prometheus.NewDesc(sensorPrefix+"co2_ppm", "Carbondioxide measurement in parts per million", labelNames, nil),
+		noise:         prometheus.NewDesc(sensorPrefix+"noise_db", "Noise measurement in decibels", labelNames, nil),
+		pressure:      prometheus.NewDesc(sensorPrefix+"pressure_mb", "Atmospheric pressure measurement in millibar", labelNames, nil),
+		rain:          prometheus.NewDesc(sensorPrefix+"rain_amount_mm", "Rain amount in millimeters", labelNames, nil),
+		windStrength:  prometheus.NewDesc(sensorPrefix+"wind_strength_kph", "Wind strength in kilometers per hour", labelNames, nil),
+		windDirection: prometheus.NewDesc(sensorPrefix+"wind_direction_degrees", "Wind direction in degrees", labelNames, nil),
+		battery:       prometheus.NewDesc(sensorPrefix+"battery_percent", "Battery remaining life (10: low)", labelNames, nil),
+		batteryVolts:  prometheus.NewDesc(sensorPrefix+"battery_volts", "Battery voltage in volts", labelNames, nil),
+		batteryState:  prometheus.NewDesc(sensorPrefix+"battery_state", "Battery state derived from the voltage and the module type", stateLabelNames, nil),
+		batteryEmpty:  prometheus.NewDesc(sensorPrefix+"battery_days_to_empty", "Estimated days until the battery state is very low, based on the voltage drop of the last days", labelNames, nil),
+		wifi:          prometheus.NewDesc(sensorPrefix+"wifi_signal_strength", "Wifi signal strength (86: bad, 71: avg, 56: good)", labelNames, nil),
+		rf:            prometheus.NewDesc(sensorPrefix+"rf_signal_strength", "RF signal strength (90: lowest, 60: highest)", labelNames, nil),
+		healthIndex:   prometheus.NewDesc(sensorPrefix+"health_index", "Air quality health index (0: Healthy, 1: Fine, 2: Fair, 3: Poor, 4: Unhealthy)", labelNames, nil),
+
+		minTemp:          prometheus.NewDesc(sensorPrefix+"min_temperature_celsius", "Minimum temperature of the current day in celsius", labelNames, nil),
+		maxTemp:          prometheus.NewDesc(sensorPrefix+"max_temperature_celsius", "Maximum temperature of the current day in celsius", labelNames, nil),
+		minTempTime:      prometheus.NewDesc(sensorPrefix+"min_temperature_time", "Time of the minimum temperature of the current day", labelNames, nil),
+		maxTempTime:      prometheus.NewDesc(sensorPrefix+"max_temperature_time", "Time of the maximum temperature of the current day", labelNames, nil),
+		absolutePressure: prometheus.NewDesc(sensorPrefix+"absolute_pressure_mb", "Absolute atmospheric pressure measurement in millibar, not reduced to sea level", labelNames, nil),
+
+		rain1h:              prometheus.NewDesc(sensorPrefix+"rain_1h_mm", "Rain amount of the last hour in millimeters", labelNames, nil),
+		rain24h:             prometheus.NewDesc(sensorPrefix+"rain_24h_mm", "Rain amount of the last 24 hours in millimeters", labelNames, nil),
+		gustStrength:        prometheus.NewDesc(sensorPrefix+"gust_strength_kph", "Strength of the strongest gust of the last 5 minutes in kilometers per hour", labelNames, nil),
+		gustDirection:       prometheus.NewDesc(sensorPrefix+"gust_direction_degrees", "Direction of the strongest gust of the last 5 minutes in degrees", labelNames, nil),
+		maxWindStrength:     prometheus.NewDesc(sensorPrefix+"max_wind_strength_kph", "Maximum wind strength of the current day in kilometers per hour", labelNames, nil),
+		maxWindStrengthTime: prometheus.NewDesc(sensorPrefix+"max_wind_strength_time", "Time of the maximum wind strength of the current day", labelNames, nil),
+		tempTrend:           prometheus.NewDesc(sensorPrefix+"temperature_trend", "Trend of the temperature as reported by Netatmo", stateLabelNames, nil),
+		pressureTrend:       prometheus.NewDesc(sensorPrefix+"pressure_trend", "Trend of the atmospheric pressure as reported by Netatmo", stateLabelNames, nil),
+
+		info:     prometheus.NewDesc(prefix+"device_info", "Information about the device or module, the value is always 1", infoLabelNames, nil),
+		location: prometheus.NewDesc(prefix+"device_location", "Location of the station the device belongs to, the value is always 1", locationLabelNames, nil),
+
+		reachable:       prometheus.NewDesc(sensorPrefix+"reachable", "One if the device or module is reachable by the Netatmo cloud", labelNames, nil),
+		lastStatusStore: prometheus.NewDesc(sensorPrefix+"last_status_store_time", "Time the last status of the station was stored by the Netatmo cloud", labelNames, nil),
+		lastSeen:        prometheus.NewDesc(sensorPrefix+"last_seen_time", "Time the module was last seen by its station", labelNames, nil),
+		lastMessage:     prometheus.NewDesc(sensorPrefix+"last_message_time", "Time of the last message the module sent to its station", labelNames, nil),
+		dataAge:         prometheus.NewDesc(sensorPrefix+"data_age_seconds", "Age of the latest measurement in seconds, also exported for stale data", labelNames, nil),
+		stale:           prometheus.NewDesc(sensorPrefix+"stale", "One if the latest measurement is older than the stale threshold", labelNames, nil),
+
+		tempFahrenheit:  prometheus.NewDesc(sensorPrefix+"temperature_fahrenheit", "Temperature measurement in fahrenheit", labelNames, nil),
+		pressureInHg:    prometheus.NewDesc(sensorPrefix+"pressure_inhg", "Atmospheric pressure measurement in inches of mercury", labelNames, nil),
+		windStrengthMPH: prometheus.NewDesc(sensorPrefix+"wind_strength_mph", "Wind strength in miles per hour", labelNames, nil),
+		gustStrengthMPH: prometheus.NewDesc(sensorPrefix+"gust_strength_mph", "Strength of the strongest gust of the last 5 minutes in miles per hour", labelNames, nil),
+		rainInches:      prometheus.NewDesc(sensorPrefix+"rain_amount_inches", "Rain amount in inches", labelNames, nil),
+	}
+}
 
+// withLabels returns a copy of labelNames with the extra label names appended.
+func withLabels(labelNames []string, extra ...string) []string {
+	result := make([]string, 0, len(labelNames)+len(extra))
+	result = append(result, labelNames...)
+	return append(result, extra...)
+}
+
+func (d v2SensorDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.updated
+	ch <- d.temp
+	ch <- d.humidity
+	ch <- d.co2
+	ch <- d.noise
+	ch <- d.pressure
+	ch <- d.rain
+	ch <- d.windStrength
+	ch <- d.windDirection
+	ch <- d.battery
+	ch <- d.batteryVolts
+	ch <- d.batteryState
+	ch <- d.batteryEmpty
+	ch <- d.wifi
+	ch <- d.rf
+	ch <- d.healthIndex
+	ch <- d.minTemp
+	ch <- d.maxTemp
+	ch <- d.minTempTime
+	ch <- d.maxTempTime
+	ch <- d.absolutePressure
+	ch <- d.rain1h
+	ch <- d.rain24h
+	ch <- d.gustStrength
+	ch <- d.gustDirection
+	ch <- d.maxWindStrength
+	ch <- d.maxWindStrengthTime
+	ch <- d.tempTrend
+	ch <- d.pressureTrend
+	ch <- d.info
+	ch <- d.location
+	ch <- d.reachable
+	ch <- d.lastStatusStore
+	ch <- d.lastSeen
+	ch <- d.lastMessage
+	ch <- d.dataAge
+	ch <- d.stale
+	ch <- d.tempFahrenheit
+	ch <- d.pressureInHg
+	ch <- d.windStrengthMPH
+	ch <- d.gustStrengthMPH
+	ch <- d.rainInches
+}
+
+// V2 unified meta metric descriptors
+var (
 	// Weather meta metrics
 	v2WeatherUpDesc               = prometheus.NewDesc(prefix+"weather_up", "Zero if there was an error during the last refresh try.", nil, nil)
 	v2WeatherRefreshIntervalDesc  = prometheus.NewDesc(prefix+"weather_refresh_interval_seconds", "Contains the configured refresh interval in seconds. This is provided as a convenience for calculations with the cache update time.", nil, nil)
@@ -51,65 +214,65 @@ var (
 
 // UnifiedCollectorV2 combines Weather and Homecoach data with unified labels
 type UnifiedCollectorV2 struct {
-	log             logrus.FieldLogger
-	weatherReader   WeatherReadFunction
-	homecoachReader HomecoachReadFunction
-	refreshInterval time.Duration
-	staleThreshold  time.Duration
-	clock           func() time.Time
-	enableWeather   bool
-	enableHomecoach bool
-
-	weatherLock                sync.RWMutex
-	weatherLastRefresh         time.Time
-	weatherLastRefreshError    error
-	weatherLastRefreshDuration time.Duration
-	weatherCachedData          *netatmo.DeviceCollection
-
-	homecoachLock                sync.RWMutex
-	homecoachLastRefresh         time.Time
-	homecoachLastRefreshError    error
-	homecoachLastRefreshDuration time.Duration
-	homecoachCachedData          *HomecoachResponse
+	log            logrus.FieldLogger
+	store          *Store
+	staleThreshold time.Duration
+	stalePolicy    StalePolicy
+	favorites      bool
+	units          UnitSystem
+	desc           v2SensorDescs
+	batteries      *batteryHistory
+	clock          func() time.Time
 }
 
-func UnifiedCollector(
-	log logrus.FieldLogger,
-	weatherReader WeatherReadFunction,
-	homecoachReader HomecoachReadFunction,
-	refreshInterval, staleThreshold time.Duration,
-	enableWeather, enableHomecoach bool,
-) *UnifiedCollectorV2 {
+// UnifiedCollector creates a UnifiedCollectorV2. The stale policy defines how devices with data older than
+// the stale threshold are exported. If favorites is set, the sensor metrics get an additional "owned" label,
+// which is false for the favorite stations of the user. Depending on the unit system, measurements are exported
+// in imperial units as well.
+func UnifiedCollector(log logrus.FieldLogger, store *Store, staleThreshold time.Duration, stalePolicy StalePolicy, favorites bool, units UnitSystem) *UnifiedCollectorV2 {
+	labelNames := LabelNames()
+	if favorites {
+		labelNames = append(labelNames, OwnedLabel)
+	}
+
 	return &UnifiedCollectorV2{
-		log:             log,
-		weatherReader:   weatherReader,
-		homecoachReader: homecoachReader,
-		refreshInterval: refreshInterval,
-		staleThreshold:  staleThreshold,
-		clock:           time.Now,
-		enableWeather:   enableWeather,
-		enableHomecoach: enableHomecoach,
+		log:            log,
+		store:          store,
+		staleThreshold: staleThreshold,
+		stalePolicy:    stalePolicy,
+		favorites:      favorites,
+		units:          units,
+		desc:           newV2SensorDescs(labelNames),
+		batteries:      newBatteryHistory(),
+		clock:          time.Now,
+	}
+}
+
+// OnRefresh is a RefreshHook which adds the battery voltages of the weather modules to the history used for
+// estimating the remaining battery life.
+func (c *UnifiedCollectorV2) OnRefresh(name string, _, current time.Time) {
+	if name != DeviceClassWeather {
+		return
+	}
+
+	data := c.store.Weather().Data
+	if data == nil {
+		return
+	}
+
+	for _, details := range data.Details {
+		if details.BatteryVP != nil {
+			c.batteries.add(details.ID, current, *details.BatteryVP)
+		}
 	}
 }
 
 func (c *UnifiedCollectorV2) Describe(ch chan<- *prometheus.Desc) {
 	// Sensor data descriptors
-	ch <- v2UpdatedDesc
-	ch <- v2TempDesc
-	ch <- v2HumidityDesc
-	ch <- v2CO2Desc
-	ch <- v2NoiseDesc
-	ch <- v2PressureDesc
-	ch <- v2RainDesc
-	ch <- v2WindStrengthDesc
-	ch <- v2WindDirectionDesc
-	ch <- v2BatteryDesc
-	ch <- v2WifiDesc
-	ch <- v2RFDesc
-	ch <- v2HealthIndexDesc
+	c.desc.describe(ch)
 
 	// Weather meta descriptors
-	if c.enableWeather {
+	if c.store.WeatherEnabled() {
 		ch <- v2WeatherUpDesc
 		ch <- v2WeatherRefreshIntervalDesc
 		ch <- v2WeatherRefreshTimestampDesc
@@ -118,7 +281,7 @@ func (c *UnifiedCollectorV2) Describe(ch chan<- *prometheus.Desc) {
 	}
 
 	// Homecoach meta descriptors
-	if c.enableHomecoach {
+	if c.store.HomecoachEnabled() {
 		ch <- v2HomecoachUpDesc
 		ch <- v2HomecoachRefreshIntervalDesc
 		ch <- v2HomecoachRefreshTimestampDesc
@@ -128,129 +291,84 @@ func (c *UnifiedCollectorV2) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (c *UnifiedCollectorV2) Collect(ch chan<- prometheus.Metric) {
-	now := c.clock()
-
-	if c.enableWeather {
-		if now.Sub(c.weatherLastRefresh) >= c.refreshInterval {
-			go c.refreshWeather(now)
-		}
-		c.collectWeatherMetaV2(ch)
-		c.collectWeatherV2(ch)
+	if c.store.WeatherEnabled() {
+		snapshot := c.store.Weather()
+		c.collectWeatherMetaV2(ch, snapshot)
+		c.collectWeatherV2(ch, snapshot.Data)
 	}
 
-	if c.enableHomecoach {
-		if now.Sub(c.homecoachLastRefresh) >= c.refreshInterval {
-			go c.refreshHomecoach(now)
-		}
-		c.collectHomecoachMetaV2(ch)
-		c.collectHomecoachV2(ch)
+	if c.store.HomecoachEnabled() {
+		snapshot := c.store.Homecoach()
+		c.collectHomecoachMetaV2(ch, snapshot)
+		c.collectHomecoachV2(ch, snapshot.Data)
 	}
 }
 
-func (c *UnifiedCollectorV2) refreshWeather(now time.Time) {
-	c.log.Debugf("V2: refreshing weather data")
-
-	start := c.clock()
-	defer func() {
-		c.weatherLock.Lock()
-		c.weatherLastRefreshDuration = c.clock().Sub(start)
-		c.weatherLock.Unlock()
-	}()
-
-	data, err := c.weatherReader()
-
-	c.weatherLock.Lock()
-	c.weatherLastRefresh = now
-	c.weatherLastRefreshError = err
-	if err != nil {
-		c.weatherLock.Unlock()
-		c.log.Errorf("V2 Weather: error during refresh: %s", err)
-		return
-	}
-	c.weatherCachedData = data
-	c.weatherLock.Unlock()
-}
-
-func (c *UnifiedCollectorV2) refreshHomecoach(now time.Time) {
-	c.log.Debugf("V2: refreshing Homecoach data")
-
-	start := c.clock()
-	defer func() {
-		c.homecoachLock.Lock()
-		c.homecoachLastRefreshDuration = c.clock().Sub(start)
-		c.homecoachLock.Unlock()
-	}()
-
-	data, err := c.homecoachReader()
-
-	c.homecoachLock.Lock()
-	c.homecoachLastRefresh = now
-	c.homecoachLastRefreshError = err
-	if err != nil {
-		c.homecoachLock.Unlock()
-		c.log.Errorf("V2 Homecoach: error during refresh: %s", err)
-		return
-	}
-	c.homecoachCachedData = data
-	c.homecoachLock.Unlock()
-}
-
-func (c *UnifiedCollectorV2) collectWeatherMetaV2(ch chan<- prometheus.Metric) {
-	c.weatherLock.RLock()
-	defer c.weatherLock.RUnlock()
-
+func (c *UnifiedCollectorV2) collectWeatherMetaV2(ch chan<- prometheus.Metric, snapshot Snapshot[WeatherResponse]) {
 	upValue := 1.0
-	if c.weatherLastRefresh.IsZero() || c.weatherLastRefreshError != nil {
+	if !snapshot.Up() {
 		upValue = 0
 	}
 
 	sendMetric(c.log, ch, v2WeatherUpDesc, prometheus.GaugeValue, upValue)
-	sendMetric(c.log, ch, v2WeatherRefreshIntervalDesc, prometheus.GaugeValue, c.refreshInterval.Seconds())
-	sendMetric(c.log, ch, v2WeatherRefreshTimestampDesc, prometheus.GaugeValue, convertTime(c.weatherLastRefresh))
-	sendMetric(c.log, ch, v2WeatherRefreshDurationDesc, prometheus.GaugeValue, c.weatherLastRefreshDuration.Seconds())
-	sendMetric(c.log, ch, v2WeatherCacheTimestampDesc, prometheus.GaugeValue, convertTime(c.weatherLastRefresh))
+	sendMetric(c.log, ch, v2WeatherRefreshIntervalDesc, prometheus.GaugeValue, c.store.RefreshInterval().Seconds())
+	sendMetric(c.log, ch, v2WeatherRefreshTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.LastRefresh))
+	sendMetric(c.log, ch, v2WeatherRefreshDurationDesc, prometheus.GaugeValue, snapshot.LastRefreshDuration.Seconds())
+	sendMetric(c.log, ch, v2WeatherCacheTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.CacheTimestamp))
 }
 
-func (c *UnifiedCollectorV2) collectHomecoachMetaV2(ch chan<- prometheus.Metric) {
-	c.homecoachLock.RLock()
-	defer c.homecoachLock.RUnlock()
-
+func (c *UnifiedCollectorV2) collectHomecoachMetaV2(ch chan<- prometheus.Metric, snapshot Snapshot[HomecoachResponse]) {
 	upValue := 1.0
-	if c.homecoachLastRefresh.IsZero() || c.homecoachLastRefreshError != nil {
+	if !snapshot.Up() {
 		upValue = 0
 	}
 
 	sendMetric(c.log, ch, v2HomecoachUpDesc, prometheus.GaugeValue, upValue)
-	sendMetric(c.log, ch, v2HomecoachRefreshIntervalDesc, prometheus.GaugeValue, c.refreshInterval.Seconds())
-	sendMetric(c.log, ch, v2HomecoachRefreshTimestampDesc, prometheus.GaugeValue, convertTime(c.homecoachLastRefresh))
-	sendMetric(c.log, ch, v2HomecoachRefreshDurationDesc, prometheus.GaugeValue, c.homecoachLastRefreshDuration.Seconds())
-	sendMetric(c.log, ch, v2HomecoachCacheTimestampDesc, prometheus.GaugeValue, convertTime(c.homecoachLastRefresh))
+	sendMetric(c.log, ch, v2HomecoachRefreshIntervalDesc, prometheus.GaugeValue, c.store.RefreshInterval().Seconds())
+	sendMetric(c.log, ch, v2HomecoachRefreshTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.LastRefresh))
+	sendMetric(c.log, ch, v2HomecoachRefreshDurationDesc, prometheus.GaugeValue, snapshot.LastRefreshDuration.Seconds())
+	sendMetric(c.log, ch, v2HomecoachCacheTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.CacheTimestamp))
 }
 
-func (c *UnifiedCollectorV2) collectWeatherV2(ch chan<- prometheus.Metric) {
-	c.weatherLock.RLock()
-	defer c.weatherLock.RUnlock()
-
-	if c.weatherCachedData == nil {
+func (c *UnifiedCollectorV2) collectWeatherV2(ch chan<- prometheus.Metric, data *WeatherResponse) {
+	if data == nil {
 		return
 	}
 
-	for _, dev := range c.weatherCachedData.Devices() {
+	units := c.units.imperial(data.Administrative)
+	for _, dev := range data.Devices() {
 		homeName := dev.HomeName
 		stationName := dev.StationName //nolint: staticcheck
+		// Favorite stations are read-only, their modules inherit this from the station.
+		owned := !dev.ReadOnly
+		// Only the station contains the place, which is used for its modules as well.
+		place := data.DeviceDetails(dev.ID).Place
 
-		c.collectWeatherDeviceV2(ch, dev, stationName, homeName)
+		c.collectWeatherDeviceV2(ch, dev, data.DeviceDetails(dev.ID), units, place, stationName, homeName, owned)
 		for _, module := range dev.LinkedModules {
-			c.collectWeatherDeviceV2(ch, module, stationName, homeName)
+			c.collectWeatherDeviceV2(ch, module, data.DeviceDetails(module.ID), units, place, stationName, homeName, owned)
 		}
 	}
 }
 
-func (c *UnifiedCollectorV2) collectWeatherDeviceV2(ch chan<- prometheus.Metric, device *netatmo.Device, stationName, homeName string) {
-	moduleName := device.ModuleName
-	if moduleName == "" {
-		moduleName = "id-" + device.ID
+func (c *UnifiedCollectorV2) collectWeatherDeviceV2(ch chan<- prometheus.Metric, device *netatmo.Device, details WeatherDetails, units imperialUnits, place Place, stationName, homeName string, owned bool) {
+	moduleName := weatherModuleName(device.ModuleName, device.ID)
+	// Unified labels: device_class, device_id, home, module, station
+	labels := c.sensorLabels(owned, "weather", device.ID, homeName, moduleName, stationName)
+
+	// Modules only have the time of their last setup.
+	dateSetup := details.DateSetup
+	if dateSetup == 0 {
+		dateSetup = details.LastSetup
+	}
+	c.sendDeviceInfo(ch, device.Type, details.Firmware, dateSetup, details.LastUpgrade, place, labels)
+
+	if details.Reachable != nil {
+		sendMetric(c.log, ch, c.desc.reachable, prometheus.GaugeValue, boolValue(*details.Reachable), labels...)
 	}
+	c.sendTimestamp(ch, c.desc.lastStatusStore, details.LastStatusStore, labels)
+	c.sendTimestamp(ch, c.desc.lastSeen, details.LastSeen, labels)
+	c.sendTimestamp(ch, c.desc.lastMessage, details.LastMessage, labels)
 
 	data := device.DashboardData
 	if data.LastMeasure == nil {
@@ -259,75 +377,262 @@ func (c *UnifiedCollectorV2) collectWeatherDeviceV2(ch chan<- prometheus.Metric,
 
 	date := time.Unix(*data.LastMeasure, 0)
 	dataAge := c.clock().Sub(date)
-	if dataAge > c.staleThreshold {
-		c.log.Debugf("V2: Data stale for %s: %s > %s", moduleName, dataAge, c.staleThreshold)
+	// The data age is sent before the stale check, so stale devices can be detected.
+	sendMetric(c.log, ch, c.desc.dataAge, prometheus.GaugeValue, dataAge.Seconds(), labels...)
+	if !c.sendStale(ch, moduleName, dataAge, labels) {
 		return
 	}
 
-	// Unified labels: device_class, device_id, home, module, station
-	labels := []string{"weather", device.ID, homeName, moduleName, stationName}
-
-	sendMetric(c.log, ch, v2UpdatedDesc, prometheus.GaugeValue, float64(date.UTC().Unix()), labels...)
+	sendMetric(c.log, ch, c.desc.updated, prometheus.GaugeValue, float64(date.UTC().Unix()), labels...)
 
 	if data.Temperature != nil {
-		sendMetric(c.log, ch, v2TempDesc, prometheus.GaugeValue, float64(*data.Temperature), labels...)
+		sendMetric(c.log, ch, c.desc.temp, prometheus.GaugeValue, float64(*data.Temperature), labels...)
+		if units.temperature {
+			sendMetric(c.log, ch, c.desc.tempFahrenheit, prometheus.GaugeValue, celsiusToFahrenheit(float64(*data.Temperature)), labels...)
+		}
 	}
 	if data.Humidity != nil {
-		sendMetric(c.log, ch, v2HumidityDesc, prometheus.GaugeValue, float64(*data.Humidity), labels...)
+		sendMetric(c.log, ch, c.desc.humidity, prometheus.GaugeValue, float64(*data.Humidity), labels...)
 	}
 	if data.CO2 != nil {
-		sendMetric(c.log, ch, v2CO2Desc, prometheus.GaugeValue, float64(*data.CO2), labels...)
+		sendMetric(c.log, ch, c.desc.co2, prometheus.GaugeValue, float64(*data.CO2), labels...)
 	}
 	if data.Noise != nil {
-		sendMetric(c.log, ch, v2NoiseDesc, prometheus.GaugeValue, float64(*data.Noise), labels...)
+		sendMetric(c.log, ch, c.desc.noise, prometheus.GaugeValue, float64(*data.Noise), labels...)
 	}
 	if data.Pressure != nil {
-		sendMetric(c.log, ch, v2PressureDesc, prometheus.GaugeValue, float64(*data.Pressure), labels...)
+		sendMetric(c.log, ch, c.desc.pressure, prometheus.GaugeValue, float64(*data.Pressure), labels...)
+		if units.pressure {
+			sendMetric(c.log, ch, c.desc.pressureInHg, prometheus.GaugeValue, mbarToInHg(float64(*data.Pressure)), labels...)
+		}
+	}
+	if data.AbsolutePressure != nil {
+		sendMetric(c.log, ch, c.desc.absolutePressure, prometheus.GaugeValue, float64(*data.AbsolutePressure), labels...)
+	}
+	if dd := details.DashboardData; dd.MinTemp != nil && dd.DateMinTemp != nil {
+		sendMetric(c.log, ch, c.desc.minTemp, prometheus.GaugeValue, *dd.MinTemp, labels...)
+		sendMetric(c.log, ch, c.desc.minTempTime, prometheus.GaugeValue, float64(*dd.DateMinTemp), labels...)
+	}
+	if dd := details.DashboardData; dd.MaxTemp != nil && dd.DateMaxTemp != nil {
+		sendMetric(c.log, ch, c.desc.maxTemp, prometheus.GaugeValue, *dd.MaxTemp, labels...)
+		sendMetric(c.log, ch, c.desc.maxTempTime, prometheus.GaugeValue, float64(*dd.DateMaxTemp), labels...)
 	}
 	if data.WindStrength != nil {
-		sendMetric(c.log, ch, v2WindStrengthDesc, prometheus.GaugeValue, float64(*data.WindStrength), labels...)
+		sendMetric(c.log, ch, c.desc.windStrength, prometheus.GaugeValue, float64(*data.WindStrength), labels...)
+		if units.windStrength {
+			sendMetric(c.log, ch, c.desc.windStrengthMPH, prometheus.GaugeValue, kphToMPH(float64(*data.WindStrength)), labels...)
+		}
 	}
 	if data.WindAngle != nil {
-		sendMetric(c.log, ch, v2WindDirectionDesc, prometheus.GaugeValue, float64(*data.WindAngle), labels...)
+		sendMetric(c.log, ch, c.desc.windDirection, prometheus.GaugeValue, float64(*data.WindAngle), labels...)
 	}
 	if data.Rain != nil {
-		sendMetric(c.log, ch, v2RainDesc, prometheus.GaugeValue, float64(*data.Rain), labels...)
+		sendMetric(c.log, ch, c.desc.rain, prometheus.GaugeValue, float64(*data.Rain), labels...)
+		if units.temperature {
+			sendMetric(c.log, ch, c.desc.rainInches, prometheus.GaugeValue, mmToInches(float64(*data.Rain)), labels...)
+		}
+	}
+	if data.Rain1Hour != nil {
+		sendMetric(c.log, ch, c.desc.rain1h, prometheus.GaugeValue, float64(*data.Rain1Hour), labels...)
+	}
+	if data.Rain1Day != nil {
+		sendMetric(c.log, ch, c.desc.rain24h, prometheus.GaugeValue, float64(*data.Rain1Day), labels...)
+	}
+	if data.GustStrength != nil {
+		sendMetric(c.log, ch, c.desc.gustStrength, prometheus.GaugeValue, float64(*data.GustStrength), labels...)
+		if units.windStrength {
+			sendMetric(c.log, ch, c.desc.gustStrengthMPH, prometheus.GaugeValue, kphToMPH(float64(*data.GustStrength)), labels...)
+		}
+	}
+	if data.GustAngle != nil {
+		sendMetric(c.log, ch, c.desc.gustDirection, prometheus.GaugeValue, float64(*data.GustAngle), labels...)
+	}
+	if dd := details.DashboardData; dd.MaxWindStrength != nil && dd.DateMaxWindStrength != nil {
+		sendMetric(c.log, ch, c.desc.maxWindStrength, prometheus.GaugeValue, *dd.MaxWindStrength, labels...)
+		sendMetric(c.log, ch, c.desc.maxWindStrengthTime, prometheus.GaugeValue, float64(*dd.DateMaxWindStrength), labels...)
+	}
+	if trend := details.DashboardData.TempTrend; trend != "" {
+		sendStateSet(c.log, ch, c.desc.tempTrend, trendStates, trend, labels...)
+	}
+	if trend := details.DashboardData.PressureTrend; trend != "" {
+		sendStateSet(c.log, ch, c.desc.pressureTrend, trendStates, trend, labels...)
+	}
+
+	// Battery and signal strength of favorite stations are of no use to the user.
+	if !owned {
+		return
 	}
+
 	if device.BatteryPercent != nil {
-		sendMetric(c.log, ch, v2BatteryDesc, prometheus.GaugeValue, float64(*device.BatteryPercent), labels...)
+		sendMetric(c.log, ch, c.desc.battery, prometheus.GaugeValue, float64(*device.BatteryPercent), labels...)
+	}
+	if details.BatteryVP != nil {
+		voltage := *details.BatteryVP
+		sendMetric(c.log, ch, c.desc.batteryVolts, prometheus.GaugeValue, float64(voltage)/1000, labels...)
+		if state, ok := batteryState(device.Type, voltage); ok {
+			sendStateSet(c.log, ch, c.desc.batteryState, batteryStates, state, labels...)
+		}
+	}
+	if days, ok := c.batteries.daysToEmpty(device.ID, device.Type); ok {
+		sendMetric(c.log, ch, c.desc.batteryEmpty, prometheus.GaugeValue, days, labels...)
 	}
 	if device.WifiStatus != nil {
-		sendMetric(c.log, ch, v2WifiDesc, prometheus.GaugeValue, float64(*device.WifiStatus), labels...)
+		sendMetric(c.log, ch, c.desc.wifi, prometheus.GaugeValue, float64(*device.WifiStatus), labels...)
 	}
 	if device.RFStatus != nil {
-		sendMetric(c.log, ch, v2RFDesc, prometheus.GaugeValue, float64(*device.RFStatus), labels...)
+		sendMetric(c.log, ch, c.desc.rf, prometheus.GaugeValue, float64(*device.RFStatus), labels...)
 	}
 }
 
-func (c *UnifiedCollectorV2) collectHomecoachV2(ch chan<- prometheus.Metric) {
-	c.homecoachLock.RLock()
-	defer c.homecoachLock.RUnlock()
-
-	if c.homecoachCachedData == nil {
+func (c *UnifiedCollectorV2) collectHomecoachV2(ch chan<- prometheus.Metric, data *HomecoachResponse) {
+	if data == nil {
 		return
 	}
 
-	for _, device := range c.homecoachCachedData.Body.Devices {
+	units := c.units.imperial(data.Body.User.Administrative)
+	for _, device := range data.Body.Devices {
 		// Unified labels: device_class, device_id, home, module, station
-		labels := []string{"homecoach", device.ID, "", "", device.StationName}
+		labels := c.sensorLabels(true, c.store.homecoachLabels(device)...)
 		dd := device.DashboardData
 
-		sendMetric(c.log, ch, v2UpdatedDesc, prometheus.GaugeValue, float64(dd.TimeUTC), labels...)
-		sendMetric(c.log, ch, v2TempDesc, prometheus.GaugeValue, float64(dd.Temperature), labels...)
-		sendMetric(c.log, ch, v2HumidityDesc, prometheus.GaugeValue, float64(dd.Humidity), labels...)
-		sendMetric(c.log, ch, v2CO2Desc, prometheus.GaugeValue, float64(dd.CO2), labels...)
-		sendMetric(c.log, ch, v2NoiseDesc, prometheus.GaugeValue, float64(dd.Noise), labels...)
-		sendMetric(c.log, ch, v2PressureDesc, prometheus.GaugeValue, float64(dd.Pressure), labels...)
-		sendMetric(c.log, ch, v2HealthIndexDesc, prometheus.GaugeValue, float64(dd.HealthIndex), labels...)
-		sendMetric(c.log, ch, v2WifiDesc, prometheus.GaugeValue, float64(device.WifiStatus), labels...)
+		firmware := device.Firmware
+		c.sendDeviceInfo(ch, device.Type, &firmware, device.DateSetup, device.LastUpgrade, device.Place, labels)
+
+		sendMetric(c.log, ch, c.desc.reachable, prometheus.GaugeValue, boolValue(device.Reachable), labels...)
+		c.sendTimestamp(ch, c.desc.lastStatusStore, device.LastStatusStore, labels)
+
+		if dd == nil || dd.TimeUTC == nil {
+			continue
+		}
+
+		date := time.Unix(*dd.TimeUTC, 0)
+		dataAge := c.clock().Sub(date)
+		sendMetric(c.log, ch, c.desc.dataAge, prometheus.GaugeValue, dataAge.Seconds(), labels...)
+		if !c.sendStale(ch, device.StationName, dataAge, labels) {
+			continue
+		}
+
+		sendMetric(c.log, ch, c.desc.updated, prometheus.GaugeValue, float64(date.UTC().Unix()), labels...)
+		if dd.Temperature != nil && homecoachReports(device.DataType, homecoachDataTemperature) {
+			sendMetric(c.log, ch, c.desc.temp, prometheus.GaugeValue, float64(*dd.Temperature), labels...)
+			if units.temperature {
+				sendMetric(c.log, ch, c.desc.tempFahrenheit, prometheus.GaugeValue, celsiusToFahrenheit(float64(*dd.Temperature)), labels...)
+			}
+		}
+		if dd.Humidity != nil && homecoachReports(device.DataType, homecoachDataHumidity) {
+			sendMetric(c.log, ch, c.desc.humidity, prometheus.GaugeValue, float64(*dd.Humidity), labels...)
+		}
+		if dd.CO2 != nil && homecoachReports(device.DataType, homecoachDataCO2) {
+			sendMetric(c.log, ch, c.desc.co2, prometheus.GaugeValue, float64(*dd.CO2), labels...)
+		}
+		if dd.Noise != nil && homecoachReports(device.DataType, homecoachDataNoise) {
+			sendMetric(c.log, ch, c.desc.noise, prometheus.GaugeValue, float64(*dd.Noise), labels...)
+		}
+		if dd.Pressure != nil && homecoachReports(device.DataType, homecoachDataPressure) {
+			sendMetric(c.log, ch, c.desc.pressure, prometheus.GaugeValue, float64(*dd.Pressure), labels...)
+			if units.pressure {
+				sendMetric(c.log, ch, c.desc.pressureInHg, prometheus.GaugeValue, mbarToInHg(float64(*dd.Pressure)), labels...)
+			}
+		}
+		if dd.AbsolutePressure != nil && homecoachReports(device.DataType, homecoachDataPressure) {
+			sendMetric(c.log, ch, c.desc.absolutePressure, prometheus.GaugeValue, float64(*dd.AbsolutePressure), labels...)
+		}
+		if dd.HealthIndex != nil && homecoachReports(device.DataType, homecoachDataHealthIndex) {
+			sendMetric(c.log, ch, c.desc.healthIndex, prometheus.GaugeValue, float64(*dd.HealthIndex), labels...)
+		}
+		// The dates are missing if the device has not reported a minimum and maximum yet.
+		if dd.MinTemp != nil && dd.DateMinTemp != nil {
+			sendMetric(c.log, ch, c.desc.minTemp, prometheus.GaugeValue, float64(*dd.MinTemp), labels...)
+			sendMetric(c.log, ch, c.desc.minTempTime, prometheus.GaugeValue, float64(*dd.DateMinTemp), labels...)
+		}
+		if dd.MaxTemp != nil && dd.DateMaxTemp != nil {
+			sendMetric(c.log, ch, c.desc.maxTemp, prometheus.GaugeValue, float64(*dd.MaxTemp), labels...)
+			sendMetric(c.log, ch, c.desc.maxTempTime, prometheus.GaugeValue, float64(*dd.DateMaxTemp), labels...)
+		}
+		sendMetric(c.log, ch, c.desc.wifi, prometheus.GaugeValue, float64(device.WifiStatus), labels...)
 	}
 }
 
+// sendDeviceInfo sends the info metric of a device and the location metric, if the location is known.
+func (c *UnifiedCollectorV2) sendDeviceInfo(ch chan<- prometheus.Metric, deviceType string, firmware *int, dateSetup, lastUpgrade int64, place Place, labels []string) {
+	firmwareValue := ""
+	if firmware != nil {
+		firmwareValue = strconv.Itoa(*firmware)
+	}
+
+	infoLabels := withLabels(labels,
+		deviceType,
+		firmwareValue,
+		formatTimestamp(dateSetup),
+		formatTimestamp(lastUpgrade),
+		place.City,
+		place.Country,
+		place.Timezone,
+		strconv.Itoa(place.Altitude),
+	)
+	sendMetric(c.log, ch, c.desc.info, prometheus.GaugeValue, 1, infoLabels...)
+
+	// Location contains longitude and latitude, in this order.
+	if len(place.Location) == 2 {
+		locationLabels := withLabels(labels,
+			strconv.FormatFloat(place.Location[1], 'f', -1, 64),
+			strconv.FormatFloat(place.Location[0], 'f', -1, 64),
+		)
+		sendMetric(c.log, ch, c.desc.location, prometheus.GaugeValue, 1, locationLabels...)
+	}
+}
+
+// sendStale applies the stale policy to a device with the given data age. It returns false if the
+// measurements of the device should not be exported.
+func (c *UnifiedCollectorV2) sendStale(ch chan<- prometheus.Metric, name string, dataAge time.Duration, labels []string) bool {
+	stale := dataAge > c.staleThreshold
+	if stale {
+		c.log.Debugf("V2: Data stale for %s: %s > %s", name, dataAge, c.staleThreshold)
+	}
+
+	switch c.stalePolicy {
+	case StaleKeep:
+		return true
+	case StaleMark:
+		sendMetric(c.log, ch, c.desc.stale, prometheus.GaugeValue, boolValue(stale), labels...)
+		return true
+	default:
+		return !stale
+	}
+}
+
+// sendTimestamp sends a metric containing a Unix timestamp, unless it is zero.
+func (c *UnifiedCollectorV2) sendTimestamp(ch chan<- prometheus.Metric, desc *prometheus.Desc, ts int64, labels []string) {
+	if ts == 0 {
+		return
+	}
+
+	sendMetric(c.log, ch, desc, prometheus.GaugeValue, float64(ts), labels...)
+}
+
+// formatTimestamp formats a Unix timestamp as label value, which is empty for zero.
+func formatTimestamp(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+
+	return strconv.FormatInt(ts, 10)
+}
+
+// sensorLabels returns the label values of a sensor metric, adding the owned label if favorites are included.
+func (c *UnifiedCollectorV2) sensorLabels(owned bool, labelValues ...string) []string {
+	return sensorLabelValues(c.favorites, owned, labelValues...)
+}
+
+// sensorLabelValues appends the value of the owned label to the label values, if favorites are included.
+func sensorLabelValues(favorites, owned bool, labelValues ...string) []string {
+	if !favorites {
+		return labelValues
+	}
+
+	return append(labelValues, strconv.FormatBool(owned))
+}
+
 func convertTime(t time.Time) float64 {
 	if t.IsZero() {
 		return 0.0
@@ -343,3 +648,35 @@ func sendMetric(log logrus.FieldLogger, ch chan<- prometheus.Metric, desc *prome
 	}
 	ch <- m
 }
+
+// sendStateSet sends one series per state, with the value one for the current state and zero for all others.
+// The state is added as last label value. An unknown current state is sent in addition to the known states.
+func sendStateSet(log logrus.FieldLogger, ch chan<- prometheus.Metric, desc *prometheus.Desc, states []string, current string, labelValues ...string) {
+	values := make([]string, len(labelValues)+1)
+	copy(values, labelValues)
+	stateIndex := len(labelValues)
+
+	known := false
+	for _, state := range states {
+		value := 0.0
+		if state == current {
+			value = 1
+			known = true
+		}
+
+		values[stateIndex] = state
+		sendMetric(log, ch, desc, prometheus.GaugeValue, value, values...)
+	}
+
+	if !known {
+		values[stateIndex] = current
+		sendMetric(log, ch, desc, prometheus.GaugeValue, 1, values...)
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
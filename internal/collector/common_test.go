@@ -0,0 +1,403 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+// testWeatherTime is the time_utc of the measurements in testWeatherJSON.
+var testWeatherTime = time.Unix(1700000000, 0)
+
+const testWeatherJSON = `{
+	"body": {
+		"devices": [
+			{
+				"_id": "70:ee:50:00:00:01",
+				"type": "NAMain",
+				"home_id": "home1",
+				"home_name": "Home",
+				"station_name": "Home (Indoor)",
+				"module_name": "Indoor",
+				"firmware": 181,
+				"date_setup": 1600000000,
+				"last_upgrade": 1690000000,
+				"place": {"altitude": 520, "city": "Munich", "country": "DE", "timezone": "Europe/Berlin", "location": [11.5, 48.1]},
+				"reachable": true,
+				"last_status_store": 1700000050,
+				"wifi_status": 56,
+				"dashboard_data": {"time_utc": 1700000000, "Temperature": 21.5, "Humidity": 45, "CO2": 650, "Noise": 35, "Pressure": 1012.4, "AbsolutePressure": 950.25, "pressure_trend": "down"},
+				"modules": [
+					{
+						"_id": "02:00:00:00:00:01",
+						"type": "NAModule1",
+						"module_name": "Outdoor",
+						"firmware": 50,
+						"last_setup": 1600000100,
+						"reachable": false,
+						"last_seen": 1699999000,
+						"last_message": 1699999100,
+						"battery_percent": 80,
+						"battery_vp": 5250,
+						"rf_status": 70,
+						"dashboard_data": {"time_utc": 1700000000, "Temperature": 8.5, "Humidity": 85, "min_temp": 2.3, "date_min_temp": 1699941600, "max_temp": 9.1, "date_max_temp": 1699970400, "temp_trend": "stable"}
+					},
+					{
+						"_id": "05:00:00:00:00:01",
+						"type": "NAModule3",
+						"module_name": "Rain",
+						"dashboard_data": {"time_utc": 1700000000, "Rain": 0.101, "sum_rain_1": 0.5, "sum_rain_24": 3.5}
+					},
+					{
+						"_id": "06:00:00:00:00:01",
+						"type": "NAModule2",
+						"module_name": "Wind",
+						"battery_vp": 4400,
+						"dashboard_data": {"time_utc": 1700000000, "WindStrength": 12, "WindAngle": 180, "GustStrength": 25, "GustAngle": 190, "max_wind_str": 31, "date_max_wind_str": 1699960000}
+					}
+				]
+			},
+			{
+				"_id": "70:ee:50:00:00:02",
+				"type": "NAMain",
+				"home_name": "Neighbour",
+				"station_name": "Neighbour (Indoor)",
+				"module_name": "Indoor",
+				"read_only": true,
+				"wifi_status": 60,
+				"dashboard_data": {"time_utc": 1700000000, "Temperature": 22.0, "Pressure": 1012.0},
+				"modules": [
+					{
+						"_id": "02:00:00:00:00:02",
+						"type": "NAModule1",
+						"module_name": "Garden",
+						"battery_percent": 50,
+						"rf_status": 80,
+						"dashboard_data": {"time_utc": 1700000000, "Temperature": 9.0, "Humidity": 80}
+					}
+				]
+			}
+		]
+	}
+}`
+
+func newTestWeatherStore(t *testing.T, log logrus.FieldLogger) *Store {
+	t.Helper()
+
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(testWeatherJSON), &weather); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	weatherReader := func() (*WeatherResponse, error) {
+		return &weather, nil
+	}
+
+	store := NewStore(log, weatherReader, nil, nil, time.Hour)
+	store.RefreshWeather()
+
+	return store
+}
+
+func TestUnifiedCollectorFavorites(t *testing.T) {
+	log := logrus.New()
+	store := newTestWeatherStore(t, log)
+
+	want := `# HELP netatmo_sensor_battery_percent Battery remaining life (10: low)
+# TYPE netatmo_sensor_battery_percent gauge
+netatmo_sensor_battery_percent{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",owned="true",station="Home (Indoor)"} 80
+# HELP netatmo_sensor_rf_signal_strength RF signal strength (90: lowest, 60: highest)
+# TYPE netatmo_sensor_rf_signal_strength gauge
+netatmo_sensor_rf_signal_strength{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",owned="true",station="Home (Indoor)"} 70
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",owned="true",station="Home (Indoor)"} 8.5
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="02:00:00:00:00:02",home="Neighbour",module="Garden",owned="false",station="Neighbour (Indoor)"} 9
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",owned="true",station="Home (Indoor)"} 21.5
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="70:ee:50:00:00:02",home="Neighbour",module="Indoor",owned="false",station="Neighbour (Indoor)"} 22
+# HELP netatmo_sensor_wifi_signal_strength Wifi signal strength (86: bad, 71: avg, 56: good)
+# TYPE netatmo_sensor_wifi_signal_strength gauge
+netatmo_sensor_wifi_signal_strength{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",owned="true",station="Home (Indoor)"} 56
+`
+
+	metricNames := []string{
+		"netatmo_sensor_battery_percent",
+		"netatmo_sensor_rf_signal_strength",
+		"netatmo_sensor_temperature_celsius",
+		"netatmo_sensor_wifi_signal_strength",
+	}
+
+	collector := UnifiedCollector(log, store, time.Hour, StaleDrop, true, UnitsMetric)
+	collector.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWeatherMinMaxTemperature(t *testing.T) {
+	log := logrus.New()
+	store := newTestWeatherStore(t, log)
+
+	wantV1 := `# HELP netatmo_sensor_absolute_pressure_mb Absolute atmospheric pressure measurement in millibar, not reduced to sea level
+# TYPE netatmo_sensor_absolute_pressure_mb gauge
+netatmo_sensor_absolute_pressure_mb{home="Home",module="Indoor",station="Home (Indoor)"} 950.25
+# HELP netatmo_sensor_max_temperature_celsius Maximum temperature of the current day in celsius
+# TYPE netatmo_sensor_max_temperature_celsius gauge
+netatmo_sensor_max_temperature_celsius{home="Home",module="Outdoor",station="Home (Indoor)"} 9.1
+# HELP netatmo_sensor_max_temperature_time Time of the maximum temperature of the current day
+# TYPE netatmo_sensor_max_temperature_time gauge
+netatmo_sensor_max_temperature_time{home="Home",module="Outdoor",station="Home (Indoor)"} 1.6999704e+09
+# HELP netatmo_sensor_min_temperature_celsius Minimum temperature of the current day in celsius
+# TYPE netatmo_sensor_min_temperature_celsius gauge
+netatmo_sensor_min_temperature_celsius{home="Home",module="Outdoor",station="Home (Indoor)"} 2.3
+# HELP netatmo_sensor_min_temperature_time Time of the minimum temperature of the current day
+# TYPE netatmo_sensor_min_temperature_time gauge
+netatmo_sensor_min_temperature_time{home="Home",module="Outdoor",station="Home (Indoor)"} 1.6999416e+09
+`
+
+	wantV2 := `# HELP netatmo_sensor_absolute_pressure_mb Absolute atmospheric pressure measurement in millibar, not reduced to sea level
+# TYPE netatmo_sensor_absolute_pressure_mb gauge
+netatmo_sensor_absolute_pressure_mb{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 950.25
+# HELP netatmo_sensor_max_temperature_celsius Maximum temperature of the current day in celsius
+# TYPE netatmo_sensor_max_temperature_celsius gauge
+netatmo_sensor_max_temperature_celsius{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 9.1
+# HELP netatmo_sensor_max_temperature_time Time of the maximum temperature of the current day
+# TYPE netatmo_sensor_max_temperature_time gauge
+netatmo_sensor_max_temperature_time{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 1.6999704e+09
+# HELP netatmo_sensor_min_temperature_celsius Minimum temperature of the current day in celsius
+# TYPE netatmo_sensor_min_temperature_celsius gauge
+netatmo_sensor_min_temperature_celsius{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 2.3
+# HELP netatmo_sensor_min_temperature_time Time of the minimum temperature of the current day
+# TYPE netatmo_sensor_min_temperature_time gauge
+netatmo_sensor_min_temperature_time{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 1.6999416e+09
+`
+
+	metricNames := []string{
+		"netatmo_sensor_absolute_pressure_mb",
+		"netatmo_sensor_max_temperature_celsius",
+		"netatmo_sensor_max_temperature_time",
+		"netatmo_sensor_min_temperature_celsius",
+		"netatmo_sensor_min_temperature_time",
+	}
+
+	collectorV1 := NewWeatherCollector(log, store, time.Hour, StaleDrop, UnitsMetric)
+	collectorV1.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	if err := testutil.CollectAndCompare(collectorV1, strings.NewReader(wantV1), metricNames...); err != nil {
+		t.Errorf("V1: %s", err)
+	}
+
+	collectorV2 := UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsMetric)
+	collectorV2.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	if err := testutil.CollectAndCompare(collectorV2, strings.NewReader(wantV2), metricNames...); err != nil {
+		t.Errorf("V2: %s", err)
+	}
+}
+
+func TestUnifiedCollectorRainWindTrend(t *testing.T) {
+	log := logrus.New()
+	store := newTestWeatherStore(t, log)
+
+	want := `# HELP netatmo_sensor_gust_direction_degrees Direction of the strongest gust of the last 5 minutes in degrees
+# TYPE netatmo_sensor_gust_direction_degrees gauge
+netatmo_sensor_gust_direction_degrees{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",station="Home (Indoor)"} 190
+# HELP netatmo_sensor_gust_strength_kph Strength of the strongest gust of the last 5 minutes in kilometers per hour
+# TYPE netatmo_sensor_gust_strength_kph gauge
+netatmo_sensor_gust_strength_kph{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",station="Home (Indoor)"} 25
+# HELP netatmo_sensor_max_wind_strength_kph Maximum wind strength of the current day in kilometers per hour
+# TYPE netatmo_sensor_max_wind_strength_kph gauge
+netatmo_sensor_max_wind_strength_kph{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",station="Home (Indoor)"} 31
+# HELP netatmo_sensor_max_wind_strength_time Time of the maximum wind strength of the current day
+# TYPE netatmo_sensor_max_wind_strength_time gauge
+netatmo_sensor_max_wind_strength_time{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",station="Home (Indoor)"} 1.69996e+09
+# HELP netatmo_sensor_pressure_trend Trend of the atmospheric pressure as reported by Netatmo
+# TYPE netatmo_sensor_pressure_trend gauge
+netatmo_sensor_pressure_trend{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",state="down",station="Home (Indoor)"} 1
+netatmo_sensor_pressure_trend{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",state="stable",station="Home (Indoor)"} 0
+netatmo_sensor_pressure_trend{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",state="up",station="Home (Indoor)"} 0
+# HELP netatmo_sensor_rain_1h_mm Rain amount of the last hour in millimeters
+# TYPE netatmo_sensor_rain_1h_mm gauge
+netatmo_sensor_rain_1h_mm{device_class="weather",device_id="05:00:00:00:00:01",home="Home",module="Rain",station="Home (Indoor)"} 0.5
+# HELP netatmo_sensor_rain_24h_mm Rain amount of the last 24 hours in millimeters
+# TYPE netatmo_sensor_rain_24h_mm gauge
+netatmo_sensor_rain_24h_mm{device_class="weather",device_id="05:00:00:00:00:01",home="Home",module="Rain",station="Home (Indoor)"} 3.5
+# HELP netatmo_sensor_temperature_trend Trend of the temperature as reported by Netatmo
+# TYPE netatmo_sensor_temperature_trend gauge
+netatmo_sensor_temperature_trend{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",state="down",station="Home (Indoor)"} 0
+netatmo_sensor_temperature_trend{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",state="stable",station="Home (Indoor)"} 1
+netatmo_sensor_temperature_trend{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",state="up",station="Home (Indoor)"} 0
+`
+
+	metricNames := []string{
+		"netatmo_sensor_gust_direction_degrees",
+		"netatmo_sensor_gust_strength_kph",
+		"netatmo_sensor_max_wind_strength_kph",
+		"netatmo_sensor_max_wind_strength_time",
+		"netatmo_sensor_pressure_trend",
+		"netatmo_sensor_rain_1h_mm",
+		"netatmo_sensor_rain_24h_mm",
+		"netatmo_sensor_temperature_trend",
+	}
+
+	collector := UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsMetric)
+	collector.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnifiedCollectorDeviceInfo(t *testing.T) {
+	log := logrus.New()
+	store := newTestWeatherStore(t, log)
+
+	want := `# HELP netatmo_device_info Information about the device or module, the value is always 1
+# TYPE netatmo_device_info gauge
+netatmo_device_info{altitude="0",city="",country="",date_setup="",device_class="weather",device_id="02:00:00:00:00:02",firmware="",home="Neighbour",last_upgrade="",module="Garden",station="Neighbour (Indoor)",timezone="",type="NAModule1"} 1
+netatmo_device_info{altitude="0",city="",country="",date_setup="",device_class="weather",device_id="70:ee:50:00:00:02",firmware="",home="Neighbour",last_upgrade="",module="Indoor",station="Neighbour (Indoor)",timezone="",type="NAMain"} 1
+netatmo_device_info{altitude="520",city="Munich",country="DE",date_setup="",device_class="weather",device_id="05:00:00:00:00:01",firmware="",home="Home",last_upgrade="",module="Rain",station="Home (Indoor)",timezone="Europe/Berlin",type="NAModule3"} 1
+netatmo_device_info{altitude="520",city="Munich",country="DE",date_setup="",device_class="weather",device_id="06:00:00:00:00:01",firmware="",home="Home",last_upgrade="",module="Wind",station="Home (Indoor)",timezone="Europe/Berlin",type="NAModule2"} 1
+netatmo_device_info{altitude="520",city="Munich",country="DE",date_setup="1600000000",device_class="weather",device_id="70:ee:50:00:00:01",firmware="181",home="Home",last_upgrade="1690000000",module="Indoor",station="Home (Indoor)",timezone="Europe/Berlin",type="NAMain"} 1
+netatmo_device_info{altitude="520",city="Munich",country="DE",date_setup="1600000100",device_class="weather",device_id="02:00:00:00:00:01",firmware="50",home="Home",last_upgrade="",module="Outdoor",station="Home (Indoor)",timezone="Europe/Berlin",type="NAModule1"} 1
+# HELP netatmo_device_location Location of the station the device belongs to, the value is always 1
+# TYPE netatmo_device_location gauge
+netatmo_device_location{device_class="weather",device_id="02:00:00:00:00:01",home="Home",latitude="48.1",longitude="11.5",module="Outdoor",station="Home (Indoor)"} 1
+netatmo_device_location{device_class="weather",device_id="05:00:00:00:00:01",home="Home",latitude="48.1",longitude="11.5",module="Rain",station="Home (Indoor)"} 1
+netatmo_device_location{device_class="weather",device_id="06:00:00:00:00:01",home="Home",latitude="48.1",longitude="11.5",module="Wind",station="Home (Indoor)"} 1
+netatmo_device_location{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",latitude="48.1",longitude="11.5",module="Indoor",station="Home (Indoor)"} 1
+`
+
+	collector := UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsMetric)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), "netatmo_device_info", "netatmo_device_location"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnifiedCollectorReachability(t *testing.T) {
+	log := logrus.New()
+	store := newTestWeatherStore(t, log)
+
+	// The status metrics are exported for stale devices, while their measurements are dropped.
+	want := `# HELP netatmo_sensor_data_age_seconds Age of the latest measurement in seconds, also exported for stale data
+# TYPE netatmo_sensor_data_age_seconds gauge
+netatmo_sensor_data_age_seconds{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 7200
+netatmo_sensor_data_age_seconds{device_class="weather",device_id="02:00:00:00:00:02",home="Neighbour",module="Garden",station="Neighbour (Indoor)"} 7200
+netatmo_sensor_data_age_seconds{device_class="weather",device_id="05:00:00:00:00:01",home="Home",module="Rain",station="Home (Indoor)"} 7200
+netatmo_sensor_data_age_seconds{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",station="Home (Indoor)"} 7200
+netatmo_sensor_data_age_seconds{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 7200
+netatmo_sensor_data_age_seconds{device_class="weather",device_id="70:ee:50:00:00:02",home="Neighbour",module="Indoor",station="Neighbour (Indoor)"} 7200
+# HELP netatmo_sensor_last_message_time Time of the last message the module sent to its station
+# TYPE netatmo_sensor_last_message_time gauge
+netatmo_sensor_last_message_time{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 1.6999991e+09
+# HELP netatmo_sensor_last_seen_time Time the module was last seen by its station
+# TYPE netatmo_sensor_last_seen_time gauge
+netatmo_sensor_last_seen_time{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 1.699999e+09
+# HELP netatmo_sensor_last_status_store_time Time the last status of the station was stored by the Netatmo cloud
+# TYPE netatmo_sensor_last_status_store_time gauge
+netatmo_sensor_last_status_store_time{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 1.70000005e+09
+# HELP netatmo_sensor_reachable One if the device or module is reachable by the Netatmo cloud
+# TYPE netatmo_sensor_reachable gauge
+netatmo_sensor_reachable{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 0
+netatmo_sensor_reachable{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 1
+`
+
+	metricNames := []string{
+		"netatmo_sensor_data_age_seconds",
+		"netatmo_sensor_last_message_time",
+		"netatmo_sensor_last_seen_time",
+		"netatmo_sensor_last_status_store_time",
+		"netatmo_sensor_reachable",
+		"netatmo_sensor_temperature_celsius",
+	}
+
+	collector := UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsMetric)
+	collector.clock = func() time.Time {
+		return testWeatherTime.Add(2 * time.Hour)
+	}
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnifiedCollectorStalePolicy(t *testing.T) {
+	tt := []struct {
+		desc   string
+		policy StalePolicy
+		want   string
+	}{
+		{
+			desc:   "drop",
+			policy: StaleDrop,
+			want:   "",
+		},
+		{
+			desc:   "keep",
+			policy: StaleKeep,
+			want: `# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 8.5
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="02:00:00:00:00:02",home="Neighbour",module="Garden",station="Neighbour (Indoor)"} 9
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 21.5
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="70:ee:50:00:00:02",home="Neighbour",module="Indoor",station="Neighbour (Indoor)"} 22
+`,
+		},
+		{
+			desc:   "mark",
+			policy: StaleMark,
+			want: `# HELP netatmo_sensor_stale One if the latest measurement is older than the stale threshold
+# TYPE netatmo_sensor_stale gauge
+netatmo_sensor_stale{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 1
+netatmo_sensor_stale{device_class="weather",device_id="02:00:00:00:00:02",home="Neighbour",module="Garden",station="Neighbour (Indoor)"} 1
+netatmo_sensor_stale{device_class="weather",device_id="05:00:00:00:00:01",home="Home",module="Rain",station="Home (Indoor)"} 1
+netatmo_sensor_stale{device_class="weather",device_id="06:00:00:00:00:01",home="Home",module="Wind",station="Home (Indoor)"} 1
+netatmo_sensor_stale{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 1
+netatmo_sensor_stale{device_class="weather",device_id="70:ee:50:00:00:02",home="Neighbour",module="Indoor",station="Neighbour (Indoor)"} 1
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 8.5
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="02:00:00:00:00:02",home="Neighbour",module="Garden",station="Neighbour (Indoor)"} 9
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 21.5
+netatmo_sensor_temperature_celsius{device_class="weather",device_id="70:ee:50:00:00:02",home="Neighbour",module="Indoor",station="Neighbour (Indoor)"} 22
+`,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			log := logrus.New()
+			store := newTestWeatherStore(t, log)
+
+			collector := UnifiedCollector(log, store, time.Hour, tc.policy, false, UnitsMetric)
+			collector.clock = func() time.Time {
+				return testWeatherTime.Add(2 * time.Hour)
+			}
+
+			metricNames := []string{
+				"netatmo_sensor_stale",
+				"netatmo_sensor_temperature_celsius",
+			}
+			if err := testutil.CollectAndCompare(collector, strings.NewReader(tc.want), metricNames...); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
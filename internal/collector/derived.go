@@ -0,0 +1,385 @@
+package collector
+
+import (
+	"math"
+	"time"
+
+	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Netatmo device types used for pairing modules of a station.
+const (
+	typeWeatherMain    = "NAMain"
+	typeWeatherOutdoor = "NAModule1"
+	typeWeatherWind    = "NAModule2"
+	typeWeatherRain    = "NAModule3"
+	typeWeatherIndoor  = "NAModule4"
+	typeHomecoach      = "NHC"
+)
+
+// Thresholds of the indoor climate advice.
+const (
+	// surfaceTemperatureFactor is the minimum temperature factor of walls according to DIN 4108-2, used to
+	// estimate the temperature of the coldest inner wall surface.
+	surfaceTemperatureFactor = 0.7
+	// Mold can grow if the humidity at a surface stays above 80 percent, condensation is likely above 70 percent.
+	moldRiskMediumHumidity = 70
+	moldRiskHighHumidity   = 80
+	// CO2 concentrations above which an air change is recommended or necessary, see DIN EN 13779.
+	co2AirChangeRecommended = 1000
+	co2AirChangeNecessary   = 1400
+)
+
+// climateReading contains the measurements of a weather module or HomeCoach device used for derived metrics.
+// Measurements not reported by the device are nil.
+type climateReading struct {
+	// StationID is the ID of the station a module belongs to.
+	StationID string
+	ID        string
+	Type      string
+	Labels    []string
+
+	Temperature  *float64
+	Humidity     *float64
+	CO2          *float64
+	WindStrength *float64
+}
+
+// indoor checks whether the reading belongs to a device measuring the climate of a room.
+func (r climateReading) indoor() bool {
+	switch r.Type {
+	case typeWeatherMain, typeWeatherIndoor, typeHomecoach:
+		return true
+	default:
+		return false
+	}
+}
+
+// derivedDescs contains the descriptors of the derived metrics.
+type derivedDescs struct {
+	dewPoint         *prometheus.Desc
+	absoluteHumidity *prometheus.Desc
+	heatIndex        *prometheus.Desc
+	humidex          *prometheus.Desc
+	windChill        *prometheus.Desc
+
+	surfaceHumidity *prometheus.Desc
+	moldRisk        *prometheus.Desc
+	ventilate       *prometheus.Desc
+	airChange       *prometheus.Desc
+}
+
+func newDerivedDescs(labelNames []string) derivedDescs {
+	return derivedDescs{
+		dewPoint:         prometheus.NewDesc(sensorPrefix+"dew_point_celsius", "Dew point calculated from temperature and humidity in celsius", labelNames, nil),
+		absoluteHumidity: prometheus.NewDesc(sensorPrefix+"absolute_humidity_grams_per_cubic_meter", "Absolute humidity calculated from temperature and humidity in grams per cubic meter", labelNames, nil),
+		heatIndex:        prometheus.NewDesc(sensorPrefix+"heat_index_celsius", "Heat index calculated from temperature and humidity in celsius", labelNames, nil),
+		humidex:          prometheus.NewDesc(sensorPrefix+"humidex_celsius", "Humidex calculated from temperature and humidity in celsius", labelNames, nil),
+		windChill:        prometheus.NewDesc(sensorPrefix+"wind_chill_celsius", "Wind chill calculated from the outdoor temperature and the wind strength of the station in celsius", labelNames, nil),
+
+		surfaceHumidity: prometheus.NewDesc(sensorPrefix+"surface_humidity_percent", "Estimated relative humidity at the coldest wall surface of the room in percent", labelNames, nil),
+		moldRisk:        prometheus.NewDesc(sensorPrefix+"mold_risk", "Risk of surface condensation and mold growth (0: Low, 1: Medium, 2: High)", labelNames, nil),
+		ventilate:       prometheus.NewDesc(sensorPrefix+"ventilate_recommended", "One if ventilating lowers the humidity, because the outdoor absolute humidity is lower than indoors", labelNames, nil),
+		airChange:       prometheus.NewDesc(sensorPrefix+"air_change_recommended", "Air change recommendation based on the CO2 concentration (0: Not needed, 1: Recommended, 2: Necessary)", labelNames, nil),
+	}
+}
+
+// DerivedCollector exports metrics calculated from the cached weather and HomeCoach measurements,
+// using the labels of the unified collector.
+type DerivedCollector struct {
+	log            logrus.FieldLogger
+	store          *Store
+	staleThreshold time.Duration
+	stalePolicy    StalePolicy
+	favorites      bool
+	outdoorPairing map[string]string
+	desc           derivedDescs
+	clock          func() time.Time
+}
+
+// NewDerivedCollector creates a DerivedCollector. Stale measurements are only used if the stale policy keeps them.
+// If favorites is set, the metrics get the "owned" label like the unified collector.
+//
+// The outdoor pairing maps the ID of a station or HomeCoach to the ID of the outdoor module used for the
+// indoor climate advice. Stations which are not paired use their own outdoor module.
+func NewDerivedCollector(log logrus.FieldLogger, store *Store, staleThreshold time.Duration, stalePolicy StalePolicy, favorites bool, outdoorPairing map[string]string) *DerivedCollector {
+	labelNames := LabelNames()
+	if favorites {
+		labelNames = append(labelNames, OwnedLabel)
+	}
+
+	return &DerivedCollector{
+		log:            log,
+		store:          store,
+		staleThreshold: staleThreshold,
+		stalePolicy:    stalePolicy,
+		favorites:      favorites,
+		outdoorPairing: outdoorPairing,
+		desc:           newDerivedDescs(labelNames),
+		clock:          time.Now,
+	}
+}
+
+func (c *DerivedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc.dewPoint
+	ch <- c.desc.absoluteHumidity
+	ch <- c.desc.heatIndex
+	ch <- c.desc.humidex
+	ch <- c.desc.windChill
+	ch <- c.desc.surfaceHumidity
+	ch <- c.desc.moldRisk
+	ch <- c.desc.ventilate
+	ch <- c.desc.airChange
+}
+
+func (c *DerivedCollector) Collect(ch chan<- prometheus.Metric) {
+	readings := c.readings()
+
+	// The wind strength is measured by a separate module of the station.
+	windStrength := make(map[string]float64)
+	// Outdoor modules by their ID and the ID of their station.
+	outdoorByID := make(map[string]climateReading)
+	outdoorByStation := make(map[string]climateReading)
+	for _, r := range readings {
+		if r.Type == typeWeatherWind && r.WindStrength != nil {
+			windStrength[r.StationID] = *r.WindStrength
+		}
+		if r.Type == typeWeatherOutdoor {
+			outdoorByID[r.ID] = r
+			outdoorByStation[r.StationID] = r
+		}
+	}
+
+	for _, r := range readings {
+		if r.Temperature == nil {
+			continue
+		}
+		temperature := *r.Temperature
+
+		if r.Humidity != nil && *r.Humidity > 0 {
+			humidity := *r.Humidity
+			sendMetric(c.log, ch, c.desc.dewPoint, prometheus.GaugeValue, dewPoint(temperature, humidity), r.Labels...)
+			sendMetric(c.log, ch, c.desc.absoluteHumidity, prometheus.GaugeValue, absoluteHumidity(temperature, humidity), r.Labels...)
+			sendMetric(c.log, ch, c.desc.heatIndex, prometheus.GaugeValue, heatIndex(temperature, humidity), r.Labels...)
+			sendMetric(c.log, ch, c.desc.humidex, prometheus.GaugeValue, humidex(temperature, humidity), r.Labels...)
+		}
+
+		if wind, ok := windStrength[r.StationID]; ok && r.Type == typeWeatherOutdoor {
+			sendMetric(c.log, ch, c.desc.windChill, prometheus.GaugeValue, windChill(temperature, wind), r.Labels...)
+		}
+	}
+
+	for _, r := range readings {
+		if !r.indoor() {
+			continue
+		}
+
+		if r.CO2 != nil {
+			sendMetric(c.log, ch, c.desc.airChange, prometheus.GaugeValue, airChangeRecommendation(*r.CO2), r.Labels...)
+		}
+
+		outdoor, ok := outdoorByStation[r.StationID]
+		if id, paired := c.outdoorPairing[r.StationID]; paired {
+			outdoor, ok = outdoorByID[id]
+		}
+		if !ok || r.Temperature == nil || r.Humidity == nil || outdoor.Temperature == nil || outdoor.Humidity == nil {
+			continue
+		}
+
+		surface := surfaceHumidity(*r.Temperature, *r.Humidity, *outdoor.Temperature)
+		sendMetric(c.log, ch, c.desc.surfaceHumidity, prometheus.GaugeValue, surface, r.Labels...)
+		sendMetric(c.log, ch, c.desc.moldRisk, prometheus.GaugeValue, moldRisk(surface), r.Labels...)
+
+		ventilate := absoluteHumidity(*outdoor.Temperature, *outdoor.Humidity) < absoluteHumidity(*r.Temperature, *r.Humidity)
+		sendMetric(c.log, ch, c.desc.ventilate, prometheus.GaugeValue, boolValue(ventilate), r.Labels...)
+	}
+}
+
+// readings returns the measurements of all weather modules and HomeCoach devices in the store.
+func (c *DerivedCollector) readings() []climateReading {
+	var readings []climateReading
+
+	if c.store.WeatherEnabled() {
+		if data := c.store.Weather().Data; data != nil {
+			for _, dev := range data.Devices() {
+				homeName := dev.HomeName
+				stationName := dev.StationName //nolint: staticcheck
+				owned := !dev.ReadOnly
+
+				for _, module := range append([]*netatmo.Device{dev}, dev.LinkedModules...) {
+					d := module.DashboardData
+					if d.LastMeasure == nil || c.skipStale(*d.LastMeasure) {
+						continue
+					}
+
+					readings = append(readings, climateReading{
+						StationID:    dev.ID,
+						ID:           module.ID,
+						Type:         module.Type,
+						Labels:       sensorLabelValues(c.favorites, owned, DeviceClassWeather, module.ID, homeName, weatherModuleName(module.ModuleName, module.ID), stationName),
+						Temperature:  float32Value(d.Temperature),
+						Humidity:     int32Value(d.Humidity),
+						CO2:          int32Value(d.CO2),
+						WindStrength: int32Value(d.WindStrength),
+					})
+				}
+			}
+		}
+	}
+
+	if c.store.HomecoachEnabled() {
+		if data := c.store.Homecoach().Data; data != nil {
+			for _, dev := range data.Body.Devices {
+				d := dev.DashboardData
+				if d == nil || d.TimeUTC == nil || c.skipStale(*d.TimeUTC) {
+					continue
+				}
+
+				reading := climateReading{
+					StationID: dev.ID,
+					ID:        dev.ID,
+					Type:      dev.Type,
+					Labels:    sensorLabelValues(c.favorites, true, c.store.homecoachLabels(dev)...),
+				}
+				if homecoachReports(dev.DataType, homecoachDataTemperature) {
+					reading.Temperature = float32Value(d.Temperature)
+				}
+				if homecoachReports(dev.DataType, homecoachDataHumidity) {
+					reading.Humidity = int32Value(d.Humidity)
+				}
+				if homecoachReports(dev.DataType, homecoachDataCO2) {
+					reading.CO2 = int32Value(d.CO2)
+				}
+				readings = append(readings, reading)
+			}
+		}
+	}
+
+	return readings
+}
+
+// skipStale checks whether a measurement taken at the Unix timestamp is stale and should not be used.
+func (c *DerivedCollector) skipStale(ts int64) bool {
+	if c.stalePolicy == StaleKeep || c.stalePolicy == StaleMark {
+		return false
+	}
+
+	return c.clock().Sub(time.Unix(ts, 0)) > c.staleThreshold
+}
+
+func float32Value(v *float32) *float64 {
+	if v == nil {
+		return nil
+	}
+
+	f := float64(*v)
+	return &f
+}
+
+func int32Value(v *int32) *float64 {
+	if v == nil {
+		return nil
+	}
+
+	f := float64(*v)
+	return &f
+}
+
+// Coefficients of the Magnus formula, which are valid between -45 °C and 60 °C.
+const (
+	magnusA = 17.62
+	magnusB = 243.12
+)
+
+// saturationVaporPressure returns the saturation vapor pressure over water in hectopascal.
+func saturationVaporPressure(temperature float64) float64 {
+	return 6.112 * math.Exp(magnusA*temperature/(magnusB+temperature))
+}
+
+// dewPoint calculates the dew point in celsius using the Magnus formula.
+func dewPoint(temperature, humidity float64) float64 {
+	gamma := math.Log(humidity/100) + magnusA*temperature/(magnusB+temperature)
+	return magnusB * gamma / (magnusA - gamma)
+}
+
+// absoluteHumidity calculates the absolute humidity in grams per cubic meter.
+func absoluteHumidity(temperature, humidity float64) float64 {
+	vaporPressure := saturationVaporPressure(temperature) * humidity / 100
+	// 216.7 g·K/(m³·hPa) is the reciprocal of the specific gas constant of water vapor.
+	return 216.7 * vaporPressure / (273.15 + temperature)
+}
+
+// heatIndex calculates the heat index in celsius using the algorithm of the US National Weather Service.
+func heatIndex(temperature, humidity float64) float64 {
+	t := temperature*9/5 + 32
+
+	hi := 0.5 * (t + 61 + (t-68)*1.2 + humidity*0.094)
+	if (hi+t)/2 >= 80 {
+		hi = -42.379 + 2.04901523*t + 10.14333127*humidity -
+			0.22475541*t*humidity - 0.00683783*t*t -
+			0.05481717*humidity*humidity + 0.00122874*t*t*humidity +
+			0.00085282*t*humidity*humidity - 0.00000199*t*t*humidity*humidity
+
+		switch {
+		case humidity < 13 && t >= 80 && t <= 112:
+			hi -= (13 - humidity) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		case humidity > 85 && t >= 80 && t <= 87:
+			hi += (humidity - 85) / 10 * (87 - t) / 5
+		}
+	}
+
+	return (hi - 32) * 5 / 9
+}
+
+// humidex calculates the humidex as used by the Meteorological Service of Canada.
+func humidex(temperature, humidity float64) float64 {
+	vaporPressure := saturationVaporPressure(temperature) * humidity / 100
+	return temperature + 0.5555*(vaporPressure-10)
+}
+
+// windChill calculates the wind chill in celsius using the formula of the Meteorological Service of Canada.
+// The formula is only defined for temperatures up to 10 °C and wind strengths above 4.8 km/h, otherwise
+// the temperature is returned.
+func windChill(temperature, windStrength float64) float64 {
+	if temperature > 10 || windStrength <= 4.8 {
+		return temperature
+	}
+
+	v := math.Pow(windStrength, 0.16)
+	return 13.12 + 0.6215*temperature - 11.37*v + 0.3965*temperature*v
+}
+
+// surfaceHumidity estimates the relative humidity at the coldest wall surface of a room in percent. The surface
+// temperature is estimated from the indoor and outdoor temperature using the minimum temperature factor.
+func surfaceHumidity(indoorTemperature, indoorHumidity, outdoorTemperature float64) float64 {
+	surfaceTemperature := outdoorTemperature + surfaceTemperatureFactor*(indoorTemperature-outdoorTemperature)
+	vaporPressure := saturationVaporPressure(indoorTemperature) * indoorHumidity / 100
+
+	return math.Min(100, 100*vaporPressure/saturationVaporPressure(surfaceTemperature))
+}
+
+// moldRisk returns the mold risk index for the relative humidity at a surface.
+func moldRisk(surfaceHumidity float64) float64 {
+	switch {
+	case surfaceHumidity >= moldRiskHighHumidity:
+		return 2
+	case surfaceHumidity >= moldRiskMediumHumidity:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// airChangeRecommendation returns the air change recommendation for the CO2 concentration in parts per million.
+func airChangeRecommendation(co2 float64) float64 {
+	switch {
+	case co2 > co2AirChangeNecessary:
+		return 2
+	case co2 > co2AirChangeRecommended:
+		return 1
+	default:
+		return 0
+	}
+}
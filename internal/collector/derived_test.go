@@ -0,0 +1,232 @@
+package collector
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDerivedFormulas(t *testing.T) {
+	tt := []struct {
+		desc    string
+		formula func(a, b float64) float64
+		a, b    float64
+		want    float64
+	}{
+		{
+			desc:    "dew point",
+			formula: dewPoint,
+			a:       20,
+			b:       50,
+			want:    9.26,
+		},
+		{
+			desc:    "absolute humidity",
+			formula: absoluteHumidity,
+			a:       20,
+			b:       50,
+			want:    8.62,
+		},
+		{
+			desc:    "heat index mild",
+			formula: heatIndex,
+			a:       20,
+			b:       50,
+			want:    19.36,
+		},
+		{
+			desc:    "heat index hot",
+			formula: heatIndex,
+			a:       32,
+			b:       70,
+			want:    40.41,
+		},
+		{
+			desc:    "humidex",
+			formula: humidex,
+			a:       30,
+			b:       70,
+			want:    40.91,
+		},
+		{
+			desc:    "wind chill",
+			formula: windChill,
+			a:       -10,
+			b:       20,
+			want:    -17.87,
+		},
+		{
+			desc:    "wind chill warm",
+			formula: windChill,
+			a:       15,
+			b:       20,
+			want:    15,
+		},
+		{
+			desc:    "wind chill calm",
+			formula: windChill,
+			a:       -10,
+			b:       3,
+			want:    -10,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.formula(tc.a, tc.b)
+			if math.Abs(got-tc.want) > 0.01 {
+				t.Errorf("got %.3f, want %.2f", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDerivedCollector(t *testing.T) {
+	log := logrus.New()
+	store := newTestWeatherStore(t, log)
+
+	collector := NewDerivedCollector(log, store, time.Hour, StaleDrop, false, nil)
+	collector.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	tt := []struct {
+		metric string
+		want   int
+	}{
+		// Modules without humidity have no dew point.
+		{metric: "netatmo_sensor_dew_point_celsius", want: 3},
+		{metric: "netatmo_sensor_absolute_humidity_grams_per_cubic_meter", want: 3},
+		// Only the station with a wind module has a wind chill.
+		{metric: "netatmo_sensor_wind_chill_celsius", want: 1},
+	}
+
+	for _, tc := range tt {
+		if got := testutil.CollectAndCount(collector, tc.metric); got != tc.want {
+			t.Errorf("got %d series of %s, want %d", got, tc.metric, tc.want)
+		}
+	}
+
+	// Stale measurements are dropped.
+	collector.clock = func() time.Time {
+		return testWeatherTime.Add(2 * time.Hour)
+	}
+	if got := testutil.CollectAndCount(collector); got != 0 {
+		t.Errorf("got %d series for stale data, want none", got)
+	}
+}
+
+func TestIndoorAdvice(t *testing.T) {
+	tt := []struct {
+		desc               string
+		indoorTemperature  float64
+		indoorHumidity     float64
+		outdoorTemperature float64
+		wantSurface        float64
+		wantRisk           float64
+	}{
+		{
+			desc:               "dry room",
+			indoorTemperature:  21,
+			indoorHumidity:     40,
+			outdoorTemperature: 0,
+			wantSurface:        59.45,
+			wantRisk:           0,
+		},
+		{
+			desc:               "damp room",
+			indoorTemperature:  20,
+			indoorHumidity:     60,
+			outdoorTemperature: -5,
+			wantSurface:        96.74,
+			wantRisk:           2,
+		},
+		{
+			desc:               "warm outside",
+			indoorTemperature:  22,
+			indoorHumidity:     55,
+			outdoorTemperature: 25,
+			wantSurface:        52.07,
+			wantRisk:           0,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			surface := surfaceHumidity(tc.indoorTemperature, tc.indoorHumidity, tc.outdoorTemperature)
+			if math.Abs(surface-tc.wantSurface) > 0.01 {
+				t.Errorf("got surface humidity %.2f, want %.2f", surface, tc.wantSurface)
+			}
+			if risk := moldRisk(surface); risk != tc.wantRisk {
+				t.Errorf("got mold risk %v, want %v", risk, tc.wantRisk)
+			}
+		})
+	}
+}
+
+func TestDerivedCollectorIndoorAdvice(t *testing.T) {
+	log := logrus.New()
+
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(testWeatherJSON), &weather); err != nil {
+		t.Fatalf("error decoding weather test data: %s", err)
+	}
+
+	var homecoach HomecoachResponse
+	if err := json.Unmarshal([]byte(testHomecoachJSON), &homecoach); err != nil {
+		t.Fatalf("error decoding homecoach test data: %s", err)
+	}
+
+	store := NewStore(log, func() (*WeatherResponse, error) {
+		return &weather, nil
+	}, func() (*HomecoachResponse, error) {
+		return &homecoach, nil
+	}, nil, time.Hour)
+	store.RefreshWeather()
+	store.RefreshHomecoach()
+
+	// The HomeCoach uses the outdoor module of the first station, the indoor module of the second station has no humidity.
+	pairing := map[string]string{
+		"70:ee:50:00:00:10": "02:00:00:00:00:01",
+	}
+	collector := NewDerivedCollector(log, store, time.Hour, StaleDrop, false, pairing)
+	collector.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	want := `# HELP netatmo_sensor_air_change_recommended Air change recommendation based on the CO2 concentration (0: Not needed, 1: Recommended, 2: Necessary)
+# TYPE netatmo_sensor_air_change_recommended gauge
+netatmo_sensor_air_change_recommended{device_class="homecoach",device_id="70:ee:50:00:00:10",home="",module="id-70:ee:50:00:00:10",station="Bedroom"} 0
+netatmo_sensor_air_change_recommended{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 0
+# HELP netatmo_sensor_mold_risk Risk of surface condensation and mold growth (0: Low, 1: Medium, 2: High)
+# TYPE netatmo_sensor_mold_risk gauge
+netatmo_sensor_mold_risk{device_class="homecoach",device_id="70:ee:50:00:00:10",home="",module="id-70:ee:50:00:00:10",station="Bedroom"} 0
+netatmo_sensor_mold_risk{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 0
+# HELP netatmo_sensor_ventilate_recommended One if ventilating lowers the humidity, because the outdoor absolute humidity is lower than indoors
+# TYPE netatmo_sensor_ventilate_recommended gauge
+netatmo_sensor_ventilate_recommended{device_class="homecoach",device_id="70:ee:50:00:00:10",home="",module="id-70:ee:50:00:00:10",station="Bedroom"} 1
+netatmo_sensor_ventilate_recommended{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 1
+`
+
+	metricNames := []string{
+		"netatmo_sensor_air_change_recommended",
+		"netatmo_sensor_mold_risk",
+		"netatmo_sensor_ventilate_recommended",
+	}
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,171 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// DeviceClassDetector is the device class of Netatmo smoke and carbon monoxide detectors.
+const DeviceClassDetector = "detector"
+
+const (
+	eventSmoke      = "smoke"
+	eventCODetected = "co_detected"
+	eventHush       = "hush"
+	eventSoundTest  = "sound_test"
+)
+
+var (
+	detectorPrefix = prefix + "detector_"
+
+	// detectorModuleTypes contains the module types of smoke and carbon monoxide detectors.
+	detectorModuleTypes = map[string]bool{
+		"NSD": true, // Smart smoke alarm
+		"NCO": true, // Smart carbon monoxide alarm
+	}
+
+	// detectorBatteryStates contains the known values of battery_state.
+	detectorBatteryStates = []string{"full", "high", "medium", "low", "very_low"}
+
+	detectorBatteryLabelNames = append(LabelNames(), "state")
+
+	// Detector collector status metrics
+	detectorUpDesc               = prometheus.NewDesc(detectorPrefix+"up", "Zero if there was an error during the last refresh try.", nil, nil)
+	detectorRefreshIntervalDesc  = prometheus.NewDesc(detectorPrefix+"refresh_interval_seconds", "Contains the configured refresh interval in seconds. This is provided as a convenience for calculations with the cache update time.", nil, nil)
+	detectorRefreshTimestampDesc = prometheus.NewDesc(detectorPrefix+"last_refresh_time", "Contains the time of the last refresh try, successful or not.", nil, nil)
+	detectorRefreshDurationDesc  = prometheus.NewDesc(detectorPrefix+"last_refresh_duration_seconds", "Contains the time it took for the last refresh to complete, even if it was unsuccessful.", nil, nil)
+	detectorCacheTimestampDesc   = prometheus.NewDesc(detectorPrefix+"cache_updated_time", "Contains the time of the cached data.", nil, nil)
+
+	// Detector metrics
+	detectorReachableDesc    = prometheus.NewDesc(detectorPrefix+"reachable", "One if the detector is reachable by Netatmo", v2LabelNames, nil)
+	detectorBatteryStateDesc = prometheus.NewDesc(detectorPrefix+"battery_state", "Battery state as reported by Netatmo", detectorBatteryLabelNames, nil)
+	detectorAlarmDesc        = prometheus.NewDesc(detectorPrefix+"alarm", "One if the latest smoke or carbon monoxide event of the detector is an alarm", v2LabelNames, nil)
+	detectorHushedDesc       = prometheus.NewDesc(detectorPrefix+"hushed", "One if the alarm of the detector has been hushed", v2LabelNames, nil)
+	detectorLastTestDesc     = prometheus.NewDesc(detectorPrefix+"last_test_time", "Time of the latest self-test of the detector", v2LabelNames, nil)
+	detectorLastTestOKDesc   = prometheus.NewDesc(detectorPrefix+"last_test_ok", "One if the latest self-test of the detector was successful", v2LabelNames, nil)
+	detectorWifiDesc         = prometheus.NewDesc(detectorPrefix+"wifi_signal_strength", "Wifi signal strength (86: bad, 71: avg, 56: good)", v2LabelNames, nil)
+)
+
+// DetectorCollector is a Prometheus collector for Netatmo smoke and carbon monoxide detectors.
+// It uses the unified V2 labels with device_class "detector".
+type DetectorCollector struct {
+	log    logrus.FieldLogger
+	store  *Store
+	events *EventCollector
+}
+
+// NewDetectorCollector creates a DetectorCollector which reads the homes from the store. The latest
+// self-tests are read from the events as well, which keep them after they are no longer contained in the
+// events of the home. The events can be nil.
+func NewDetectorCollector(log logrus.FieldLogger, store *Store, events *EventCollector) *DetectorCollector {
+	return &DetectorCollector{
+		log:    log,
+		store:  store,
+		events: events,
+	}
+}
+
+func (c *DetectorCollector) Describe(ch chan<- *prometheus.Desc) {
+	// Status metrics
+	ch <- detectorUpDesc
+	ch <- detectorRefreshIntervalDesc
+	ch <- detectorRefreshTimestampDesc
+	ch <- detectorRefreshDurationDesc
+	ch <- detectorCacheTimestampDesc
+
+	// Data metrics
+	ch <- detectorReachableDesc
+	ch <- detectorBatteryStateDesc
+	ch <- detectorAlarmDesc
+	ch <- detectorHushedDesc
+	ch <- detectorLastTestDesc
+	ch <- detectorLastTestOKDesc
+	ch <- detectorWifiDesc
+}
+
+func (c *DetectorCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.store.Homes()
+
+	upValue := 1.0
+	if !snapshot.Up() {
+		upValue = 0
+	}
+
+	sendMetric(c.log, ch, detectorUpDesc, prometheus.GaugeValue, upValue)
+	sendMetric(c.log, ch, detectorRefreshIntervalDesc, prometheus.GaugeValue, c.store.RefreshInterval().Seconds())
+	sendMetric(c.log, ch, detectorRefreshTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.LastRefresh))
+	sendMetric(c.log, ch, detectorRefreshDurationDesc, prometheus.GaugeValue, snapshot.LastRefreshDuration.Seconds())
+	sendMetric(c.log, ch, detectorCacheTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.CacheTimestamp))
+
+	if snapshot.Data == nil {
+		return
+	}
+
+	for _, home := range snapshot.Data.Homes {
+		c.collectHome(ch, home)
+	}
+}
+
+func (c *DetectorCollector) collectHome(ch chan<- prometheus.Metric, home Home) {
+	moduleStatus := home.moduleStatus()
+	moduleNames := home.moduleNames()
+
+	for _, module := range home.Modules {
+		if !detectorModuleTypes[module.Type] {
+			continue
+		}
+
+		labels := home.moduleLabels(DeviceClassDetector, module, moduleNames)
+
+		// A detector missing from homestatus is not reachable.
+		status, ok := moduleStatus[module.ID]
+		reachable := ok && (status.Reachable == nil || *status.Reachable)
+		sendMetric(c.log, ch, detectorReachableDesc, prometheus.GaugeValue, boolValue(reachable), labels...)
+
+		if status.BatteryState != "" {
+			sendStateSet(c.log, ch, detectorBatteryStateDesc, detectorBatteryStates, status.BatteryState, labels...)
+		}
+		if status.WifiStrength != nil {
+			sendMetric(c.log, ch, detectorWifiDesc, prometheus.GaugeValue, float64(*status.WifiStrength), labels...)
+		}
+
+		// The sub_type of alarm events is zero once the alarm has ended.
+		if event, ok := home.latestEvent(module.ID, eventSmoke, eventCODetected); ok {
+			sendMetric(c.log, ch, detectorAlarmDesc, prometheus.GaugeValue, boolValue(subType(event) > 0), labels...)
+		}
+		if event, ok := home.latestEvent(module.ID, eventHush); ok {
+			sendMetric(c.log, ch, detectorHushedDesc, prometheus.GaugeValue, boolValue(subType(event) > 0), labels...)
+		}
+		if testTime, testOK, ok := c.lastTest(home, module.ID); ok {
+			sendMetric(c.log, ch, detectorLastTestDesc, prometheus.GaugeValue, float64(testTime), labels...)
+			sendMetric(c.log, ch, detectorLastTestOKDesc, prometheus.GaugeValue, boolValue(testOK), labels...)
+		}
+	}
+}
+
+// lastTest returns the time and result of the latest self-test of the detector, either from the events of
+// the home or from the tests seen before. It returns false if no test is known.
+func (c *DetectorCollector) lastTest(home Home, moduleID string) (int64, bool, bool) {
+	var testTime int64
+	var testOK, found bool
+	if event, ok := home.latestEvent(moduleID, eventSoundTest); ok {
+		testTime, testOK, found = event.Time, subType(event) == 0, true
+	}
+
+	if c.events != nil {
+		if seenTime, seenOK, ok := c.events.lastTest(moduleID); ok && seenTime > testTime {
+			testTime, testOK, found = seenTime, seenOK, true
+		}
+	}
+
+	return testTime, testOK, found
+}
+
+// subType returns the sub_type of the event, which is zero if it is missing.
+func subType(e Event) int32 {
+	if e.SubType == nil {
+		return 0
+	}
+
+	return *e.SubType
+}
@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDetectorCollector(t *testing.T) {
+	log := logrus.New()
+	store := newTestHomesStore(t, log)
+
+	want := `# HELP netatmo_detector_alarm One if the latest smoke or carbon monoxide event of the detector is an alarm
+# TYPE netatmo_detector_alarm gauge
+netatmo_detector_alarm{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",station="Hallway smoke"} 0
+# HELP netatmo_detector_battery_state Battery state as reported by Netatmo
+# TYPE netatmo_detector_battery_state gauge
+netatmo_detector_battery_state{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",state="full",station="Hallway smoke"} 1
+netatmo_detector_battery_state{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",state="high",station="Hallway smoke"} 0
+netatmo_detector_battery_state{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",state="low",station="Hallway smoke"} 0
+netatmo_detector_battery_state{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",state="medium",station="Hallway smoke"} 0
+netatmo_detector_battery_state{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",state="very_low",station="Hallway smoke"} 0
+# HELP netatmo_detector_hushed One if the alarm of the detector has been hushed
+# TYPE netatmo_detector_hushed gauge
+netatmo_detector_hushed{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",station="Hallway smoke"} 0
+# HELP netatmo_detector_last_test_ok One if the latest self-test of the detector was successful
+# TYPE netatmo_detector_last_test_ok gauge
+netatmo_detector_last_test_ok{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",station="Hallway smoke"} 1
+# HELP netatmo_detector_last_test_time Time of the latest self-test of the detector
+# TYPE netatmo_detector_last_test_time gauge
+netatmo_detector_last_test_time{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",station="Hallway smoke"} 1.699e+09
+# HELP netatmo_detector_reachable One if the detector is reachable by Netatmo
+# TYPE netatmo_detector_reachable gauge
+netatmo_detector_reachable{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",station="Hallway smoke"} 1
+netatmo_detector_reachable{device_class="detector",device_id="70:ee:50:00:00:06",home="Home",module="Kitchen CO",station="Kitchen CO"} 0
+# HELP netatmo_detector_wifi_signal_strength Wifi signal strength (86: bad, 71: avg, 56: good)
+# TYPE netatmo_detector_wifi_signal_strength gauge
+netatmo_detector_wifi_signal_strength{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",station="Hallway smoke"} 65
+`
+
+	metricNames := []string{
+		"netatmo_detector_alarm",
+		"netatmo_detector_battery_state",
+		"netatmo_detector_hushed",
+		"netatmo_detector_last_test_ok",
+		"netatmo_detector_last_test_time",
+		"netatmo_detector_reachable",
+		"netatmo_detector_wifi_signal_strength",
+	}
+
+	collector := NewDetectorCollector(log, store, nil)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDetectorCollectorKeepsLastTest(t *testing.T) {
+	var homes HomesResponse
+	if err := json.Unmarshal([]byte(testHomesJSON), &homes); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	log := logrus.New()
+	store := NewStore(log, nil, nil, func() (*HomesResponse, error) {
+		return &homes, nil
+	}, time.Hour)
+
+	events, err := NewEventCollector(log, store, filepath.Join(t.TempDir(), "events.json"))
+	if err != nil {
+		t.Fatalf("error creating event collector: %s", err)
+	}
+	store.OnRefresh(events.OnRefresh)
+	store.RefreshHomes()
+
+	// The self-test drops out of the events of the home.
+	homes.Homes[0].Events = nil
+	store.RefreshHomes()
+
+	want := `# HELP netatmo_detector_last_test_ok One if the latest self-test of the detector was successful
+# TYPE netatmo_detector_last_test_ok gauge
+netatmo_detector_last_test_ok{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",station="Hallway smoke"} 1
+# HELP netatmo_detector_last_test_time Time of the latest self-test of the detector
+# TYPE netatmo_detector_last_test_time gauge
+netatmo_detector_last_test_time{device_class="detector",device_id="70:ee:50:00:00:05",home="Home",module="Hallway smoke",station="Hallway smoke"} 1.699e+09
+`
+
+	collector := NewDetectorCollector(log, store, events)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), "netatmo_detector_last_test_ok", "netatmo_detector_last_test_time"); err != nil {
+		t.Error(err)
+	}
+}
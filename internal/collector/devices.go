@@ -0,0 +1,182 @@
+package collector
+
+// Device classes used in the device_class label of V2 metrics.
+const (
+	DeviceClassWeather   = "weather"
+	DeviceClassHomecoach = "homecoach"
+)
+
+// Place contains the location of a station as configured by the user.
+type Place struct {
+	Altitude int    `json:"altitude"`
+	City     string `json:"city"`
+	Country  string `json:"country"`
+	Timezone string `json:"timezone"`
+	// Location contains longitude and latitude.
+	Location []float64 `json:"location"`
+}
+
+// Device describes a single device or module contained in the cached data.
+type Device struct {
+	// Class is the device class, as used in the device_class label.
+	Class string
+	// ID is the ID of the device or module.
+	ID string
+	// MainDeviceID is the ID of the station a module belongs to. It is equal to ID for main devices.
+	MainDeviceID string
+	// HomeID is the ID of the home containing the device, if it is known.
+	HomeID string
+	// Type is the Netatmo device type, for example "NAMain", "NAModule1" or "NHC".
+	Type string
+	// Owned is false for favorite stations of other users and their modules.
+	Owned bool
+	// Labels contains the values of the V2 labels.
+	Labels []string
+}
+
+// Devices returns all devices and modules of the given device class which are in the cached data.
+func (s *Store) Devices(class string) []Device {
+	var devices []Device
+
+	switch class {
+	case DeviceClassWeather:
+		data := s.Weather().Data
+		if data == nil {
+			return nil
+		}
+
+		for _, dev := range data.Devices() {
+			homeName := dev.HomeName
+			stationName := dev.StationName //nolint: staticcheck
+
+			devices = append(devices, Device{
+				Class:        DeviceClassWeather,
+				ID:           dev.ID,
+				MainDeviceID: dev.ID,
+				HomeID:       dev.HomeID,
+				Type:         dev.Type,
+				Owned:        !dev.ReadOnly,
+				Labels:       []string{DeviceClassWeather, dev.ID, homeName, weatherModuleName(dev.ModuleName, dev.ID), stationName},
+			})
+
+			for _, module := range dev.LinkedModules {
+				devices = append(devices, Device{
+					Class:        DeviceClassWeather,
+					ID:           module.ID,
+					MainDeviceID: dev.ID,
+					HomeID:       dev.HomeID,
+					Type:         module.Type,
+					Owned:        !dev.ReadOnly,
+					Labels:       []string{DeviceClassWeather, module.ID, homeName, weatherModuleName(module.ModuleName, module.ID), stationName},
+				})
+			}
+		}
+	case DeviceClassHomecoach:
+		data := s.Homecoach().Data
+		if data == nil {
+			return nil
+		}
+
+		for _, dev := range data.Body.Devices {
+			home, _ := s.homeOfModule(dev.ID)
+			devices = append(devices, Device{
+				Class:        DeviceClassHomecoach,
+				ID:           dev.ID,
+				MainDeviceID: dev.ID,
+				HomeID:       home.ID,
+				Type:         dev.Type,
+				Owned:        true,
+				Labels:       s.homecoachLabels(dev),
+			})
+		}
+	}
+
+	return devices
+}
+
+// homecoachLabels returns the V2 label values of a HomeCoach device. The home is taken from the configured
+// homes, or from homesdata if the homes are read.
+func (s *Store) homecoachLabels(dev HomecoachDevice) []string {
+	homeName, ok := s.homecoachHomes[dev.ID]
+	if !ok {
+		home, _ := s.homeOfModule(dev.ID)
+		homeName = home.Name
+	}
+
+	moduleName := dev.ModuleName
+	if moduleName == "" {
+		moduleName = dev.Name
+	}
+
+	return []string{DeviceClassHomecoach, dev.ID, homeName, weatherModuleName(moduleName, dev.ID), dev.StationName}
+}
+
+// homeOfModule returns the home in homesdata containing the module. It returns false if the module is not
+// contained in any home.
+func (s *Store) homeOfModule(moduleID string) (Home, bool) {
+	data := s.Homes().Data
+	if data == nil {
+		return Home{}, false
+	}
+
+	for _, home := range data.Homes {
+		for _, module := range home.Modules {
+			if module.ID == moduleID {
+				return home, true
+			}
+		}
+	}
+
+	return Home{}, false
+}
+
+// TargetDeviceIDs returns the IDs of the devices and modules of a probe target in the cached data. The target
+// is either the home with the given ID, or the station or device with the given ID together with its modules.
+func (s *Store) TargetDeviceIDs(homeID, deviceID string) []string {
+	matches := func(home, id, mainDevice string) bool {
+		if homeID != "" {
+			return home == homeID
+		}
+
+		return id == deviceID || mainDevice == deviceID
+	}
+
+	var ids []string
+	for _, class := range []string{DeviceClassWeather, DeviceClassHomecoach} {
+		for _, device := range s.Devices(class) {
+			if matches(device.HomeID, device.ID, device.MainDeviceID) {
+				ids = append(ids, device.ID)
+			}
+		}
+	}
+
+	// Modules of the home-based collectors belong to the bridge they are connected to.
+	if data := s.Homes().Data; data != nil {
+		for _, home := range data.Homes {
+			for _, module := range home.Modules {
+				if matches(home.ID, module.ID, module.Bridge) {
+					ids = append(ids, module.ID)
+				}
+			}
+		}
+	}
+
+	return ids
+}
+
+// weatherModuleName returns the name used in the module label, falling back to the ID if the module has no name.
+func weatherModuleName(name, id string) string {
+	if name == "" {
+		return "id-" + id
+	}
+
+	return name
+}
+
+// LabelNames returns the names of the V2 labels, in the order used by Device.Labels.
+func LabelNames() []string {
+	names := make([]string, len(v2LabelNames))
+	copy(names, v2LabelNames)
+
+	return names
+}
@@ -0,0 +1,193 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// DeviceClassEnergy is the device class of Netatmo Energy rooms and modules.
+const DeviceClassEnergy = "energy"
+
+var (
+	energyPrefix = prefix + "energy_"
+
+	// energyModuleTypes contains the module types which belong to Netatmo Energy.
+	energyModuleTypes = map[string]bool{
+		"NAPlug":   true, // Relay
+		"NATherm1": true, // Thermostat
+		"NRV":      true, // Smart radiator valve
+		"OTH":      true, // OpenTherm relay
+		"OTM":      true, // OpenTherm thermostat
+		"BNS":      true, // Smarther thermostat
+	}
+
+	// setpointModes contains the known values of therm_setpoint_mode.
+	setpointModes = []string{"schedule", "manual", "max", "off", "away", "hg", "home"}
+
+	// energyBatteryStates contains the known values of battery_state.
+	energyBatteryStates = []string{"max", "full", "high", "medium", "low", "very_low"}
+
+	energyModeLabelNames    = append(LabelNames(), "mode")
+	energyBatteryLabelNames = append(LabelNames(), "state")
+
+	// Energy collector status metrics
+	energyUpDesc               = prometheus.NewDesc(energyPrefix+"up", "Zero if there was an error during the last refresh try.", nil, nil)
+	energyRefreshIntervalDesc  = prometheus.NewDesc(energyPrefix+"refresh_interval_seconds", "Contains the configured refresh interval in seconds. This is provided as a convenience for calculations with the cache update time.", nil, nil)
+	energyRefreshTimestampDesc = prometheus.NewDesc(energyPrefix+"last_refresh_time", "Contains the time of the last refresh try, successful or not.", nil, nil)
+	energyRefreshDurationDesc  = prometheus.NewDesc(energyPrefix+"last_refresh_duration_seconds", "Contains the time it took for the last refresh to complete, even if it was unsuccessful.", nil, nil)
+	energyCacheTimestampDesc   = prometheus.NewDesc(energyPrefix+"cache_updated_time", "Contains the time of the cached data.", nil, nil)
+
+	// Room metrics
+	energyRoomTemperatureDesc  = prometheus.NewDesc(energyPrefix+"room_temperature_celsius", "Measured room temperature in celsius", v2LabelNames, nil)
+	energyRoomSetpointDesc     = prometheus.NewDesc(energyPrefix+"room_setpoint_temperature_celsius", "Setpoint room temperature in celsius", v2LabelNames, nil)
+	energyRoomSetpointModeDesc = prometheus.NewDesc(energyPrefix+"room_setpoint_mode", "Current setpoint mode of the room", energyModeLabelNames, nil)
+	energyRoomHeatingPowerDesc = prometheus.NewDesc(energyPrefix+"room_heating_power_request_percent", "Heating power requested by the room in percent", v2LabelNames, nil)
+	energyRoomOpenWindowDesc   = prometheus.NewDesc(energyPrefix+"room_open_window", "One if an open window has been detected in the room", v2LabelNames, nil)
+
+	// Module metrics
+	energyBoilerStatusDesc = prometheus.NewDesc(energyPrefix+"boiler_status", "One if the boiler is currently heating", v2LabelNames, nil)
+	energyValveOpenDesc    = prometheus.NewDesc(energyPrefix+"valve_open_percent", "Opening of the radiator valve in percent, as requested for the room of the valve", v2LabelNames, nil)
+	energyBatteryLevelDesc = prometheus.NewDesc(energyPrefix+"battery_level_millivolts", "Battery level in millivolts", v2LabelNames, nil)
+	energyBatteryStateDesc = prometheus.NewDesc(energyPrefix+"battery_state", "Battery state as reported by Netatmo", energyBatteryLabelNames, nil)
+	energyRFDesc           = prometheus.NewDesc(energyPrefix+"rf_signal_strength", "RF signal strength (90: lowest, 60: highest)", v2LabelNames, nil)
+	energyWifiDesc         = prometheus.NewDesc(energyPrefix+"wifi_signal_strength", "Wifi signal strength (86: bad, 71: avg, 56: good)", v2LabelNames, nil)
+)
+
+// EnergyCollector is a Prometheus collector for Netatmo Energy thermostats, relays and smart radiator valves.
+// It uses the unified V2 labels with device_class "energy".
+type EnergyCollector struct {
+	log   logrus.FieldLogger
+	store *Store
+}
+
+// NewEnergyCollector creates an EnergyCollector which reads the homes from the store.
+func NewEnergyCollector(log logrus.FieldLogger, store *Store) *EnergyCollector {
+	return &EnergyCollector{
+		log:   log,
+		store: store,
+	}
+}
+
+func (c *EnergyCollector) Describe(ch chan<- *prometheus.Desc) {
+	// Status metrics
+	ch <- energyUpDesc
+	ch <- energyRefreshIntervalDesc
+	ch <- energyRefreshTimestampDesc
+	ch <- energyRefreshDurationDesc
+	ch <- energyCacheTimestampDesc
+
+	// Data metrics
+	ch <- energyRoomTemperatureDesc
+	ch <- energyRoomSetpointDesc
+	ch <- energyRoomSetpointModeDesc
+	ch <- energyRoomHeatingPowerDesc
+	ch <- energyRoomOpenWindowDesc
+	ch <- energyBoilerStatusDesc
+	ch <- energyValveOpenDesc
+	ch <- energyBatteryLevelDesc
+	ch <- energyBatteryStateDesc
+	ch <- energyRFDesc
+	ch <- energyWifiDesc
+}
+
+func (c *EnergyCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.store.Homes()
+
+	upValue := 1.0
+	if !snapshot.Up() {
+		upValue = 0
+	}
+
+	sendMetric(c.log, ch, energyUpDesc, prometheus.GaugeValue, upValue)
+	sendMetric(c.log, ch, energyRefreshIntervalDesc, prometheus.GaugeValue, c.store.RefreshInterval().Seconds())
+	sendMetric(c.log, ch, energyRefreshTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.LastRefresh))
+	sendMetric(c.log, ch, energyRefreshDurationDesc, prometheus.GaugeValue, snapshot.LastRefreshDuration.Seconds())
+	sendMetric(c.log, ch, energyCacheTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.CacheTimestamp))
+
+	if snapshot.Data == nil {
+		return
+	}
+
+	for _, home := range snapshot.Data.Homes {
+		c.collectHome(ch, home)
+	}
+}
+
+func (c *EnergyCollector) collectHome(ch chan<- prometheus.Metric, home Home) {
+	roomStatus := home.roomStatus()
+	moduleStatus := home.moduleStatus()
+	moduleNames := home.moduleNames()
+
+	hasEnergyModules := false
+	for _, module := range home.Modules {
+		if energyModuleTypes[module.Type] {
+			hasEnergyModules = true
+			break
+		}
+	}
+
+	if !hasEnergyModules {
+		return
+	}
+
+	for _, room := range home.Rooms {
+		status, ok := roomStatus[room.ID]
+		if !ok {
+			continue
+		}
+
+		// Rooms use the room ID and name in place of the module
+		labels := []string{DeviceClassEnergy, room.ID, home.Name, room.Name, ""}
+
+		if status.ThermMeasuredTemperature != nil {
+			sendMetric(c.log, ch, energyRoomTemperatureDesc, prometheus.GaugeValue, *status.ThermMeasuredTemperature, labels...)
+		}
+		if status.ThermSetpointTemperature != nil {
+			sendMetric(c.log, ch, energyRoomSetpointDesc, prometheus.GaugeValue, *status.ThermSetpointTemperature, labels...)
+		}
+		if status.ThermSetpointMode != "" {
+			sendStateSet(c.log, ch, energyRoomSetpointModeDesc, setpointModes, status.ThermSetpointMode, labels...)
+		}
+		if status.HeatingPowerRequest != nil {
+			sendMetric(c.log, ch, energyRoomHeatingPowerDesc, prometheus.GaugeValue, float64(*status.HeatingPowerRequest), labels...)
+		}
+		if status.OpenWindow != nil {
+			sendMetric(c.log, ch, energyRoomOpenWindowDesc, prometheus.GaugeValue, boolValue(*status.OpenWindow), labels...)
+		}
+	}
+
+	for _, module := range home.Modules {
+		if !energyModuleTypes[module.Type] {
+			continue
+		}
+
+		status, ok := moduleStatus[module.ID]
+		if !ok {
+			continue
+		}
+
+		labels := home.moduleLabels(DeviceClassEnergy, module, moduleNames)
+
+		if status.BoilerStatus != nil {
+			sendMetric(c.log, ch, energyBoilerStatusDesc, prometheus.GaugeValue, boolValue(*status.BoilerStatus), labels...)
+		}
+		if module.Type == "NRV" {
+			// Netatmo does not report the position of a single valve, only the heating power request of its room.
+			if room, ok := roomStatus[module.RoomID]; ok && room.HeatingPowerRequest != nil {
+				sendMetric(c.log, ch, energyValveOpenDesc, prometheus.GaugeValue, float64(*room.HeatingPowerRequest), labels...)
+			}
+		}
+		if status.BatteryLevel != nil {
+			sendMetric(c.log, ch, energyBatteryLevelDesc, prometheus.GaugeValue, float64(*status.BatteryLevel), labels...)
+		}
+		if status.BatteryState != "" {
+			sendStateSet(c.log, ch, energyBatteryStateDesc, energyBatteryStates, status.BatteryState, labels...)
+		}
+		if status.RFStrength != nil {
+			sendMetric(c.log, ch, energyRFDesc, prometheus.GaugeValue, float64(*status.RFStrength), labels...)
+		}
+		if status.WifiStrength != nil {
+			sendMetric(c.log, ch, energyWifiDesc, prometheus.GaugeValue, float64(*status.WifiStrength), labels...)
+		}
+	}
+}
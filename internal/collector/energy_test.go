@@ -0,0 +1,139 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+const testHomesJSON = `{
+  "homes": [
+    {
+      "id": "home1",
+      "name": "Home",
+      "rooms": [
+        {"id": "room1", "name": "Living room", "type": "livingroom", "module_ids": ["04:00:00:00:00:01"]}
+      ],
+      "modules": [
+        {"id": "70:ee:50:00:00:01", "type": "NAPlug", "name": "Relay"},
+        {"id": "04:00:00:00:00:01", "type": "NRV", "name": "Valve", "room_id": "room1", "bridge": "70:ee:50:00:00:01"},
+        {"id": "70:ee:50:00:00:02", "type": "NACamera", "name": "Camera"},
+        {"id": "70:ee:50:00:00:03", "type": "NOC", "name": "Garden"},
+        {"id": "70:00:00:00:00:04", "type": "NACamDoorTag", "name": "Front door", "bridge": "70:ee:50:00:00:02"},
+        {"id": "70:ee:50:00:00:05", "type": "NSD", "name": "Hallway smoke"},
+        {"id": "70:ee:50:00:00:06", "type": "NCO", "name": "Kitchen CO"}
+      ],
+      "status": {
+        "rooms": [
+          {"id": "room1", "therm_measured_temperature": 20.5, "therm_setpoint_temperature": 21, "therm_setpoint_mode": "schedule", "heating_power_request": 40, "open_window": false}
+        ],
+        "modules": [
+          {"id": "70:ee:50:00:00:01", "type": "NAPlug", "wifi_strength": 55},
+          {"id": "04:00:00:00:00:01", "type": "NRV", "battery_level": 2900, "battery_state": "high", "rf_strength": 70},
+          {"id": "70:ee:50:00:00:02", "type": "NACamera", "monitoring": "on", "sd_status": 4, "alim_status": 2, "wifi_strength": 60},
+          {"id": "70:ee:50:00:00:03", "type": "NOC", "monitoring": "off", "sd_status": 1, "alim_status": 2, "light_mode_status": "auto"},
+          {"id": "70:00:00:00:00:04", "type": "NACamDoorTag", "status": "open", "battery_state": "low", "rf_strength": 80},
+          {"id": "70:ee:50:00:00:05", "type": "NSD", "reachable": true, "battery_state": "full", "wifi_strength": 65}
+        ]
+      },
+      "events": [
+        {"id": "e2", "type": "movement", "time": 1700000100, "module_id": "70:ee:50:00:00:02"},
+        {"id": "e1", "type": "person", "time": 1700000000, "module_id": "70:ee:50:00:00:02"},
+        {"id": "e5", "type": "hush", "sub_type": 0, "time": 1700000300, "module_id": "70:ee:50:00:00:05"},
+        {"id": "e4", "type": "smoke", "sub_type": 0, "time": 1700000250, "module_id": "70:ee:50:00:00:05"},
+        {"id": "e3", "type": "smoke", "sub_type": 1, "time": 1700000200, "module_id": "70:ee:50:00:00:05"},
+        {"id": "e0", "type": "sound_test", "sub_type": 0, "time": 1699000000, "module_id": "70:ee:50:00:00:05"}
+      ]
+    }
+  ]
+}`
+
+// newTestHomesStore returns a store containing the test homes.
+func newTestHomesStore(t *testing.T, log logrus.FieldLogger) *Store {
+	t.Helper()
+
+	var homes HomesResponse
+	if err := json.Unmarshal([]byte(testHomesJSON), &homes); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	homesReader := func() (*HomesResponse, error) {
+		return &homes, nil
+	}
+
+	store := NewStore(log, nil, nil, homesReader, time.Hour)
+	store.RefreshHomes()
+
+	return store
+}
+
+func TestEnergyCollector(t *testing.T) {
+	log := logrus.New()
+	store := newTestHomesStore(t, log)
+
+	want := `# HELP netatmo_energy_battery_level_millivolts Battery level in millivolts
+# TYPE netatmo_energy_battery_level_millivolts gauge
+netatmo_energy_battery_level_millivolts{device_class="energy",device_id="04:00:00:00:00:01",home="Home",module="Valve",station="Relay"} 2900
+# HELP netatmo_energy_battery_state Battery state as reported by Netatmo
+# TYPE netatmo_energy_battery_state gauge
+netatmo_energy_battery_state{device_class="energy",device_id="04:00:00:00:00:01",home="Home",module="Valve",state="full",station="Relay"} 0
+netatmo_energy_battery_state{device_class="energy",device_id="04:00:00:00:00:01",home="Home",module="Valve",state="high",station="Relay"} 1
+netatmo_energy_battery_state{device_class="energy",device_id="04:00:00:00:00:01",home="Home",module="Valve",state="low",station="Relay"} 0
+netatmo_energy_battery_state{device_class="energy",device_id="04:00:00:00:00:01",home="Home",module="Valve",state="max",station="Relay"} 0
+netatmo_energy_battery_state{device_class="energy",device_id="04:00:00:00:00:01",home="Home",module="Valve",state="medium",station="Relay"} 0
+netatmo_energy_battery_state{device_class="energy",device_id="04:00:00:00:00:01",home="Home",module="Valve",state="very_low",station="Relay"} 0
+# HELP netatmo_energy_rf_signal_strength RF signal strength (90: lowest, 60: highest)
+# TYPE netatmo_energy_rf_signal_strength gauge
+netatmo_energy_rf_signal_strength{device_class="energy",device_id="04:00:00:00:00:01",home="Home",module="Valve",station="Relay"} 70
+# HELP netatmo_energy_room_heating_power_request_percent Heating power requested by the room in percent
+# TYPE netatmo_energy_room_heating_power_request_percent gauge
+netatmo_energy_room_heating_power_request_percent{device_class="energy",device_id="room1",home="Home",module="Living room",station=""} 40
+# HELP netatmo_energy_room_open_window One if an open window has been detected in the room
+# TYPE netatmo_energy_room_open_window gauge
+netatmo_energy_room_open_window{device_class="energy",device_id="room1",home="Home",module="Living room",station=""} 0
+# HELP netatmo_energy_room_setpoint_mode Current setpoint mode of the room
+# TYPE netatmo_energy_room_setpoint_mode gauge
+netatmo_energy_room_setpoint_mode{device_class="energy",device_id="room1",home="Home",mode="away",module="Living room",station=""} 0
+netatmo_energy_room_setpoint_mode{device_class="energy",device_id="room1",home="Home",mode="hg",module="Living room",station=""} 0
+netatmo_energy_room_setpoint_mode{device_class="energy",device_id="room1",home="Home",mode="home",module="Living room",station=""} 0
+netatmo_energy_room_setpoint_mode{device_class="energy",device_id="room1",home="Home",mode="manual",module="Living room",station=""} 0
+netatmo_energy_room_setpoint_mode{device_class="energy",device_id="room1",home="Home",mode="max",module="Living room",station=""} 0
+netatmo_energy_room_setpoint_mode{device_class="energy",device_id="room1",home="Home",mode="off",module="Living room",station=""} 0
+netatmo_energy_room_setpoint_mode{device_class="energy",device_id="room1",home="Home",mode="schedule",module="Living room",station=""} 1
+# HELP netatmo_energy_room_setpoint_temperature_celsius Setpoint room temperature in celsius
+# TYPE netatmo_energy_room_setpoint_temperature_celsius gauge
+netatmo_energy_room_setpoint_temperature_celsius{device_class="energy",device_id="room1",home="Home",module="Living room",station=""} 21
+# HELP netatmo_energy_room_temperature_celsius Measured room temperature in celsius
+# TYPE netatmo_energy_room_temperature_celsius gauge
+netatmo_energy_room_temperature_celsius{device_class="energy",device_id="room1",home="Home",module="Living room",station=""} 20.5
+# HELP netatmo_energy_valve_open_percent Opening of the radiator valve in percent, as requested for the room of the valve
+# TYPE netatmo_energy_valve_open_percent gauge
+netatmo_energy_valve_open_percent{device_class="energy",device_id="04:00:00:00:00:01",home="Home",module="Valve",station="Relay"} 40
+# HELP netatmo_energy_wifi_signal_strength Wifi signal strength (86: bad, 71: avg, 56: good)
+# TYPE netatmo_energy_wifi_signal_strength gauge
+netatmo_energy_wifi_signal_strength{device_class="energy",device_id="70:ee:50:00:00:01",home="Home",module="Relay",station="Relay"} 55
+`
+
+	metricNames := []string{
+		"netatmo_energy_battery_level_millivolts",
+		"netatmo_energy_battery_state",
+		"netatmo_energy_boiler_status",
+		"netatmo_energy_rf_signal_strength",
+		"netatmo_energy_room_heating_power_request_percent",
+		"netatmo_energy_room_open_window",
+		"netatmo_energy_room_setpoint_mode",
+		"netatmo_energy_room_setpoint_temperature_celsius",
+		"netatmo_energy_room_temperature_celsius",
+		"netatmo_energy_valve_open_percent",
+		"netatmo_energy_wifi_signal_strength",
+	}
+
+	collector := NewEnergyCollector(log, store)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
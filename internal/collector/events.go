@@ -0,0 +1,319 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// batteryReplacedVoltageJump is the minimum rise of the battery voltage in millivolts which is detected
+	// as battery replacement.
+	batteryReplacedVoltageJump = 400
+	// batteryReplacedPercentJump is used instead for modules which do not report their voltage.
+	batteryReplacedPercentJump = 30
+)
+
+var (
+	eventBatteryReplacedDesc = prometheus.NewDesc(prefix+"module_battery_replaced_time", "Time the battery replacement of the module was detected", v2LabelNames, nil)
+	eventFirmwareChangedDesc = prometheus.NewDesc(prefix+"device_firmware_changed_time", "Time the firmware change of the device or module was detected", v2LabelNames, nil)
+)
+
+// moduleState contains the values of a device or module which are compared between refreshes, together with
+// the time of the detected events as Unix timestamps.
+type moduleState struct {
+	Firmware       int   `json:"firmware,omitempty"`
+	LastUpgrade    int64 `json:"last_upgrade,omitempty"`
+	BatteryVP      int   `json:"battery_vp,omitempty"`
+	BatteryPercent int   `json:"battery_percent,omitempty"`
+
+	// LastTest is the time of the latest self-test of a detector, which is kept after the test event has
+	// dropped out of the events of the home.
+	LastTest       int64 `json:"last_test,omitempty"`
+	LastTestFailed bool  `json:"last_test_failed,omitempty"`
+
+	BatteryReplaced int64 `json:"battery_replaced,omitempty"`
+	FirmwareChanged int64 `json:"firmware_changed,omitempty"`
+}
+
+// update compares the state with the values read at the given time and returns the updated state.
+// Values which are not reported keep their previous value.
+func (s moduleState) update(current moduleState, now time.Time) moduleState {
+	switch {
+	case current.BatteryVP != 0 && s.BatteryVP != 0:
+		if current.BatteryVP-s.BatteryVP >= batteryReplacedVoltageJump {
+			s.BatteryReplaced = now.Unix()
+		}
+	case current.BatteryPercent != 0 && s.BatteryPercent != 0:
+		if current.BatteryPercent-s.BatteryPercent >= batteryReplacedPercentJump {
+			s.BatteryReplaced = now.Unix()
+		}
+	}
+
+	if current.LastUpgrade != 0 && s.LastUpgrade != 0 && current.LastUpgrade != s.LastUpgrade {
+		// The time of the upgrade is reported by Netatmo.
+		s.FirmwareChanged = current.LastUpgrade
+	} else if current.Firmware != 0 && s.Firmware != 0 && current.Firmware != s.Firmware {
+		s.FirmwareChanged = now.Unix()
+	}
+
+	if current.LastTest > s.LastTest {
+		s.LastTest = current.LastTest
+		s.LastTestFailed = current.LastTestFailed
+	}
+
+	if current.Firmware != 0 {
+		s.Firmware = current.Firmware
+	}
+	if current.LastUpgrade != 0 {
+		s.LastUpgrade = current.LastUpgrade
+	}
+	if current.BatteryVP != 0 {
+		s.BatteryVP = current.BatteryVP
+	}
+	if current.BatteryPercent != 0 {
+		s.BatteryPercent = current.BatteryPercent
+	}
+
+	return s
+}
+
+// EventCollector detects battery replacements and firmware changes of weather modules and HomeCoach devices
+// by comparing their state between refreshes. It also keeps the latest self-test of smoke and carbon monoxide
+// detectors. The state is persisted in a state file, so that events are detected across restarts of the
+// exporter.
+type EventCollector struct {
+	log       logrus.FieldLogger
+	store     *Store
+	stateFile string
+
+	lock  sync.Mutex
+	state map[string]moduleState
+}
+
+// NewEventCollector creates an EventCollector using the state file. The state is updated by OnRefresh,
+// which needs to be added as hook to the store.
+func NewEventCollector(log logrus.FieldLogger, store *Store, stateFile string) (*EventCollector, error) {
+	state, err := loadModuleState(stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventCollector{
+		log:       log,
+		store:     store,
+		stateFile: stateFile,
+		state:     state,
+	}, nil
+}
+
+// OnRefresh is a RefreshHook which compares the state of the devices with the state of the last refresh.
+func (c *EventCollector) OnRefresh(name string, _, current time.Time) {
+	var states map[string]moduleState
+	switch name {
+	case DeviceClassWeather:
+		states = weatherModuleStates(c.store.Weather().Data)
+	case DeviceClassHomecoach:
+		states = homecoachModuleStates(c.store.Homecoach().Data)
+	case endpointHomes:
+		states = detectorModuleStates(c.store.Homes().Data)
+	default:
+		return
+	}
+
+	if len(states) == 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	changed := false
+	for id, state := range states {
+		previous, ok := c.state[id]
+		updated := previous.update(state, current)
+		if !ok || updated != previous {
+			c.state[id] = updated
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	if err := saveModuleState(c.stateFile, c.state); err != nil {
+		c.log.Errorf("Error saving module state: %s", err)
+	}
+}
+
+// weatherModuleStates returns the state of the owned weather stations and modules by ID.
+func weatherModuleStates(data *WeatherResponse) map[string]moduleState {
+	if data == nil {
+		return nil
+	}
+
+	states := make(map[string]moduleState)
+	for _, dev := range data.Devices() {
+		// The state of favorite stations is of no use to the user.
+		if dev.ReadOnly {
+			continue
+		}
+
+		states[dev.ID] = weatherModuleState(data.DeviceDetails(dev.ID), dev.BatteryPercent)
+		for _, module := range dev.LinkedModules {
+			states[module.ID] = weatherModuleState(data.DeviceDetails(module.ID), module.BatteryPercent)
+		}
+	}
+
+	return states
+}
+
+func weatherModuleState(details WeatherDetails, batteryPercent *int32) moduleState {
+	state := moduleState{
+		LastUpgrade: details.LastUpgrade,
+	}
+	if details.Firmware != nil {
+		state.Firmware = *details.Firmware
+	}
+	if details.BatteryVP != nil {
+		state.BatteryVP = *details.BatteryVP
+	}
+	if batteryPercent != nil {
+		state.BatteryPercent = int(*batteryPercent)
+	}
+
+	return state
+}
+
+// homecoachModuleStates returns the state of the HomeCoach devices by ID.
+func homecoachModuleStates(data *HomecoachResponse) map[string]moduleState {
+	if data == nil {
+		return nil
+	}
+
+	states := make(map[string]moduleState)
+	for _, dev := range data.Body.Devices {
+		states[dev.ID] = moduleState{
+			Firmware:    dev.Firmware,
+			LastUpgrade: dev.LastUpgrade,
+		}
+	}
+
+	return states
+}
+
+// detectorModuleStates returns the latest self-test of the detectors by ID, if it is contained in the events.
+func detectorModuleStates(data *HomesResponse) map[string]moduleState {
+	if data == nil {
+		return nil
+	}
+
+	states := make(map[string]moduleState)
+	for _, home := range data.Homes {
+		for _, module := range home.Modules {
+			if !detectorModuleTypes[module.Type] {
+				continue
+			}
+
+			if event, ok := home.latestEvent(module.ID, eventSoundTest); ok {
+				states[module.ID] = moduleState{
+					LastTest:       event.Time,
+					LastTestFailed: subType(event) != 0,
+				}
+			}
+		}
+	}
+
+	return states
+}
+
+// lastTest returns the time and result of the latest self-test of the detector. It returns false if no test
+// has been seen yet.
+func (c *EventCollector) lastTest(moduleID string) (int64, bool, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state := c.state[moduleID]
+	if state.LastTest == 0 {
+		return 0, false, false
+	}
+
+	return state.LastTest, !state.LastTestFailed, true
+}
+
+func (c *EventCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- eventBatteryReplacedDesc
+	ch <- eventFirmwareChangedDesc
+}
+
+func (c *EventCollector) Collect(ch chan<- prometheus.Metric) {
+	var devices []Device
+	if c.store.WeatherEnabled() {
+		devices = append(devices, c.store.Devices(DeviceClassWeather)...)
+	}
+	if c.store.HomecoachEnabled() {
+		devices = append(devices, c.store.Devices(DeviceClassHomecoach)...)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, device := range devices {
+		state, ok := c.state[device.ID]
+		if !ok || !device.Owned {
+			continue
+		}
+
+		if state.BatteryReplaced != 0 {
+			sendMetric(c.log, ch, eventBatteryReplacedDesc, prometheus.GaugeValue, float64(state.BatteryReplaced), device.Labels...)
+		}
+		if state.FirmwareChanged != 0 {
+			sendMetric(c.log, ch, eventFirmwareChangedDesc, prometheus.GaugeValue, float64(state.FirmwareChanged), device.Labels...)
+		}
+	}
+}
+
+func loadModuleState(fileName string) (map[string]moduleState, error) {
+	state := map[string]moduleState{}
+
+	data, err := os.ReadFile(fileName)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return state, nil
+	case err != nil:
+		return nil, fmt.Errorf("error reading module state: %w", err)
+	default:
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error decoding module state: %w", err)
+	}
+
+	return state, nil
+}
+
+func saveModuleState(fileName string, state map[string]moduleState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error marshalling module state: %w", err)
+	}
+
+	// The state is written to a temporary file first, so that a failed write does not truncate the old state.
+	tempFile := fileName + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0o600); err != nil {
+		return fmt.Errorf("error writing module state: %w", err)
+	}
+
+	if err := os.Rename(tempFile, fileName); err != nil {
+		return fmt.Errorf("error replacing module state: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestModuleStateUpdate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		desc    string
+		state   moduleState
+		current moduleState
+		want    moduleState
+	}{
+		{
+			desc:    "first refresh",
+			current: moduleState{Firmware: 50, BatteryVP: 4500},
+			want:    moduleState{Firmware: 50, BatteryVP: 4500},
+		},
+		{
+			desc:    "battery voltage dropping",
+			state:   moduleState{BatteryVP: 4500},
+			current: moduleState{BatteryVP: 4480},
+			want:    moduleState{BatteryVP: 4480},
+		},
+		{
+			desc:    "battery voltage jump",
+			state:   moduleState{BatteryVP: 4500, BatteryPercent: 20},
+			current: moduleState{BatteryVP: 6000, BatteryPercent: 100},
+			want:    moduleState{BatteryVP: 6000, BatteryPercent: 100, BatteryReplaced: 1700000000},
+		},
+		{
+			desc:    "battery percent jump",
+			state:   moduleState{BatteryPercent: 20},
+			current: moduleState{BatteryPercent: 100},
+			want:    moduleState{BatteryPercent: 100, BatteryReplaced: 1700000000},
+		},
+		{
+			desc:    "battery not reported",
+			state:   moduleState{BatteryVP: 4500},
+			current: moduleState{},
+			want:    moduleState{BatteryVP: 4500},
+		},
+		{
+			desc:    "firmware change",
+			state:   moduleState{Firmware: 50, BatteryReplaced: 1600000000},
+			current: moduleState{Firmware: 51},
+			want:    moduleState{Firmware: 51, BatteryReplaced: 1600000000, FirmwareChanged: 1700000000},
+		},
+		{
+			desc:    "last upgrade change",
+			state:   moduleState{Firmware: 180, LastUpgrade: 1690000000},
+			current: moduleState{Firmware: 181, LastUpgrade: 1699990000},
+			want:    moduleState{Firmware: 181, LastUpgrade: 1699990000, FirmwareChanged: 1699990000},
+		},
+		{
+			desc:    "new self-test",
+			state:   moduleState{LastTest: 1690000000},
+			current: moduleState{LastTest: 1699000000, LastTestFailed: true},
+			want:    moduleState{LastTest: 1699000000, LastTestFailed: true},
+		},
+		{
+			desc:    "self-test not reported",
+			state:   moduleState{LastTest: 1699000000, LastTestFailed: true},
+			current: moduleState{},
+			want:    moduleState{LastTest: 1699000000, LastTestFailed: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.state.update(tc.current, now)
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEventCollector(t *testing.T) {
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(testWeatherJSON), &weather); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	now := testWeatherTime
+	log := logrus.New()
+	store := NewStore(log, func() (*WeatherResponse, error) {
+		return &weather, nil
+	}, nil, nil, time.Hour)
+	store.clock = func() time.Time {
+		return now
+	}
+
+	stateFile := filepath.Join(t.TempDir(), "events.json")
+	collector, err := NewEventCollector(log, store, stateFile)
+	if err != nil {
+		t.Fatalf("error creating collector: %s", err)
+	}
+	store.OnRefresh(collector.OnRefresh)
+	store.RefreshWeather()
+
+	// New batteries in the outdoor module and a firmware upgrade of the station
+	voltage := 6100
+	firmware := 182
+	outdoor := weather.Details["02:00:00:00:00:01"]
+	outdoor.BatteryVP = &voltage
+	weather.Details["02:00:00:00:00:01"] = outdoor
+	station := weather.Details["70:ee:50:00:00:01"]
+	station.Firmware = &firmware
+	weather.Details["70:ee:50:00:00:01"] = station
+
+	now = testWeatherTime.Add(time.Hour)
+	store.RefreshWeather()
+
+	want := `# HELP netatmo_device_firmware_changed_time Time the firmware change of the device or module was detected
+# TYPE netatmo_device_firmware_changed_time gauge
+netatmo_device_firmware_changed_time{device_class="weather",device_id="70:ee:50:00:00:01",home="Home",module="Indoor",station="Home (Indoor)"} 1.7000036e+09
+# HELP netatmo_module_battery_replaced_time Time the battery replacement of the module was detected
+# TYPE netatmo_module_battery_replaced_time gauge
+netatmo_module_battery_replaced_time{device_class="weather",device_id="02:00:00:00:00:01",home="Home",module="Outdoor",station="Home (Indoor)"} 1.7000036e+09
+`
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+
+	// The events are restored from the state file.
+	restored, err := NewEventCollector(log, store, stateFile)
+	if err != nil {
+		t.Fatalf("error restoring collector: %s", err)
+	}
+
+	if err := testutil.CollectAndCompare(restored, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEventCollectorSavesChanges(t *testing.T) {
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(testWeatherJSON), &weather); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	log := logrus.New()
+	store := NewStore(log, func() (*WeatherResponse, error) {
+		return &weather, nil
+	}, nil, nil, time.Hour)
+
+	stateFile := filepath.Join(t.TempDir(), "events.json")
+	collector, err := NewEventCollector(log, store, stateFile)
+	if err != nil {
+		t.Fatalf("error creating collector: %s", err)
+	}
+	store.OnRefresh(collector.OnRefresh)
+
+	store.RefreshWeather()
+	if _, err := os.Stat(stateFile); err != nil {
+		t.Fatalf("state file not written: %s", err)
+	}
+
+	// Nothing changed, so the state file is not written again.
+	if err := os.Remove(stateFile); err != nil {
+		t.Fatalf("error removing state file: %s", err)
+	}
+	store.RefreshWeather()
+	if _, err := os.Stat(stateFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got error %v for unchanged state, want state file not written", err)
+	}
+
+	firmware := 182
+	station := weather.Details["70:ee:50:00:00:01"]
+	station.Firmware = &firmware
+	weather.Details["70:ee:50:00:00:01"] = station
+
+	store.RefreshWeather()
+	if _, err := os.Stat(stateFile); err != nil {
+		t.Errorf("state file not written after change: %s", err)
+	}
+	if _, err := os.Stat(stateFile + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got error %v for temporary file, want it to be removed", err)
+	}
+}
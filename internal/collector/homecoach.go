@@ -1,13 +1,13 @@
 package collector
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"sync"
+	"slices"
 	"time"
 
+	"github.com/marc825/netatmo-exporter/v2/internal/api"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
@@ -96,34 +96,87 @@ var (
 		homecoachLabels,
 		nil,
 	)
+
+	homecoachAbsolutePressureDesc = prometheus.NewDesc(
+		prefix+"homecoach_absolute_pressure",
+		"Netatmo Home Coach measured absolute pressure in mb, not reduced to sea level.",
+		homecoachLabels,
+		nil,
+	)
+
+	homecoachMinTempDesc = prometheus.NewDesc(
+		prefix+"homecoach_min_temperature",
+		"Netatmo Home Coach minimum temperature of the current day in degrees Celsius.",
+		homecoachLabels,
+		nil,
+	)
+
+	homecoachMaxTempDesc = prometheus.NewDesc(
+		prefix+"homecoach_max_temperature",
+		"Netatmo Home Coach maximum temperature of the current day in degrees Celsius.",
+		homecoachLabels,
+		nil,
+	)
+
+	homecoachMinTempTimeDesc = prometheus.NewDesc(
+		prefix+"homecoach_min_temperature_time",
+		"Time of the Netatmo Home Coach minimum temperature of the current day.",
+		homecoachLabels,
+		nil,
+	)
+
+	homecoachMaxTempTimeDesc = prometheus.NewDesc(
+		prefix+"homecoach_max_temperature_time",
+		"Time of the Netatmo Home Coach maximum temperature of the current day.",
+		homecoachLabels,
+		nil,
+	)
+
+	homecoachTemperatureFahrenheitDesc = prometheus.NewDesc(
+		prefix+"homecoach_temperature_fahrenheit",
+		"Netatmo Home Coach measured temperature in degrees Fahrenheit.",
+		homecoachLabels,
+		nil,
+	)
+
+	homecoachPressureInHgDesc = prometheus.NewDesc(
+		prefix+"homecoach_pressure_inhg",
+		"Netatmo Home Coach measured pressure in inches of mercury.",
+		homecoachLabels,
+		nil,
+	)
+
+	homecoachStaleDesc = prometheus.NewDesc(
+		prefix+"homecoach_stale",
+		"One if the latest Netatmo Home Coach measurement is older than the stale threshold.",
+		homecoachLabels,
+		nil,
+	)
 )
 
 // HomecoachReadFunction defines the interface for reading HomeCoach data from the Netatmo API.
 type HomecoachReadFunction func() (*HomecoachResponse, error)
 
+// HomeCoachCollector is a Prometheus collector for Netatmo HomeCoach values.
 type HomeCoachCollector struct {
-	log             logrus.FieldLogger
-	readFunction    HomecoachReadFunction
-	RefreshInterval time.Duration
-	StaleThreshold  time.Duration
-	clock           func() time.Time
-
-	lastRefresh         time.Time
-	lastRefreshError    error
-	lastRefreshDuration time.Duration
-
-	cacheLock      sync.RWMutex
-	cacheTimestamp time.Time
-	cachedData     *HomecoachResponse
+	log            logrus.FieldLogger
+	store          *Store
+	StaleThreshold time.Duration
+	StalePolicy    StalePolicy
+	Units          UnitSystem
+	clock          func() time.Time
 }
 
-func NewHomecoachCollector(log logrus.FieldLogger, readFunction HomecoachReadFunction, refreshInterval, staleDuration time.Duration) *HomeCoachCollector {
+// NewHomecoachCollector creates a HomeCoachCollector which reads the HomeCoach data from the store.
+// Depending on the unit system, measurements are exported in imperial units as well.
+func NewHomecoachCollector(log logrus.FieldLogger, store *Store, staleDuration time.Duration, stalePolicy StalePolicy, units UnitSystem) *HomeCoachCollector {
 	return &HomeCoachCollector{
-		log:             log,
-		readFunction:    readFunction,
-		RefreshInterval: refreshInterval,
-		StaleThreshold:  staleDuration,
-		clock:           time.Now,
+		log:            log,
+		store:          store,
+		StaleThreshold: staleDuration,
+		StalePolicy:    stalePolicy,
+		Units:          units,
+		clock:          time.Now,
 	}
 }
 
@@ -143,122 +196,166 @@ func (c *HomeCoachCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- homecoachPressureDesc
 	ch <- homecoachHealthIndexDesc
 	ch <- homecoachWifiDesc
+	ch <- homecoachAbsolutePressureDesc
+	ch <- homecoachMinTempDesc
+	ch <- homecoachMaxTempDesc
+	ch <- homecoachMinTempTimeDesc
+	ch <- homecoachMaxTempTimeDesc
+	ch <- homecoachTemperatureFahrenheitDesc
+	ch <- homecoachPressureInHgDesc
+	ch <- homecoachStaleDesc
 }
 
 func (c *HomeCoachCollector) Collect(ch chan<- prometheus.Metric) {
-	now := c.clock()
-	if now.Sub(c.lastRefresh) >= c.RefreshInterval {
-		go c.refreshData(now)
-	}
+	snapshot := c.store.Homecoach()
 
 	upValue := 1.0
-	if c.lastRefresh.IsZero() || c.lastRefreshError != nil {
+	if !snapshot.Up() {
 		upValue = 0
 	}
 
 	sendMetric(c.log, ch, homecoachUpDesc, prometheus.GaugeValue, upValue)
-	sendMetric(c.log, ch, homecoachRefreshIntervalDesc, prometheus.GaugeValue, c.RefreshInterval.Seconds())
-	sendMetric(c.log, ch, homecoachRefreshTimestampDesc, prometheus.GaugeValue, convertTime(c.lastRefresh))
-	sendMetric(c.log, ch, homecoachRefreshDurationDesc, prometheus.GaugeValue, c.lastRefreshDuration.Seconds())
-
-	c.cacheLock.RLock()
-	defer c.cacheLock.RUnlock()
+	sendMetric(c.log, ch, homecoachRefreshIntervalDesc, prometheus.GaugeValue, c.store.RefreshInterval().Seconds())
+	sendMetric(c.log, ch, homecoachRefreshTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.LastRefresh))
+	sendMetric(c.log, ch, homecoachRefreshDurationDesc, prometheus.GaugeValue, snapshot.LastRefreshDuration.Seconds())
+	sendMetric(c.log, ch, homecoachCacheTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.CacheTimestamp))
 
-	sendMetric(c.log, ch, homecoachCacheTimestampDesc, prometheus.GaugeValue, convertTime(c.cacheTimestamp))
-	if c.cachedData == nil {
+	if snapshot.Data == nil {
 		return
 	}
 
-	for _, device := range c.cachedData.Body.Devices {
+	units := c.Units.imperial(snapshot.Data.Body.User.Administrative)
+	for _, device := range snapshot.Data.Body.Devices {
 		// only device_id and device_name
 		labels := []string{device.ID, device.StationName}
 
-		sendMetric(c.log, ch, homecoachTemperatureDesc, prometheus.GaugeValue, float64(device.DashboardData.Temperature), labels...)
-		sendMetric(c.log, ch, homecoachHumidityDesc, prometheus.GaugeValue, float64(device.DashboardData.Humidity), labels...)
-		sendMetric(c.log, ch, homecoachCO2Desc, prometheus.GaugeValue, float64(device.DashboardData.CO2), labels...)
-		sendMetric(c.log, ch, homecoachNoiseDesc, prometheus.GaugeValue, float64(device.DashboardData.Noise), labels...)
-		sendMetric(c.log, ch, homecoachPressureDesc, prometheus.GaugeValue, float64(device.DashboardData.Pressure), labels...)
-		sendMetric(c.log, ch, homecoachHealthIndexDesc, prometheus.GaugeValue, float64(device.DashboardData.HealthIndex), labels...)
-		sendMetric(c.log, ch, homecoachWifiDesc, prometheus.GaugeValue, float64(device.WifiStatus), labels...)
-	}
-}
+		dd := device.DashboardData
+		if dd == nil || dd.TimeUTC == nil {
+			c.log.Debugf("No data available for %s.", device.StationName)
+			continue
+		}
 
-func (c *HomeCoachCollector) refreshData(now time.Time) {
-	c.log.Debugf("HomeCoachCollector: refreshing data. Time since last refresh: %s", now.Sub(c.lastRefresh))
-	c.lastRefresh = now
+		dataAge := c.clock().Sub(time.Unix(*dd.TimeUTC, 0))
+		stale := dataAge > c.StaleThreshold
+		if stale {
+			c.log.Debugf("Data is stale for %s: %s > %s", device.StationName, dataAge, c.StaleThreshold)
+		}
 
-	defer func(start time.Time) {
-		c.lastRefreshDuration = c.clock().Sub(start)
-	}(c.clock())
+		switch c.StalePolicy {
+		case StaleKeep:
+		case StaleMark:
+			sendMetric(c.log, ch, homecoachStaleDesc, prometheus.GaugeValue, boolValue(stale), labels...)
+		default:
+			if stale {
+				continue
+			}
+		}
 
-	data, err := c.readFunction()
-	c.lastRefreshError = err
-	if err != nil {
-		c.log.Errorf("HomeCoachCollector: error during refresh: %s", err)
-		return
-	}
+		if dd.Temperature != nil && homecoachReports(device.DataType, homecoachDataTemperature) {
+			sendMetric(c.log, ch, homecoachTemperatureDesc, prometheus.GaugeValue, float64(*dd.Temperature), labels...)
+			if units.temperature {
+				sendMetric(c.log, ch, homecoachTemperatureFahrenheitDesc, prometheus.GaugeValue, celsiusToFahrenheit(float64(*dd.Temperature)), labels...)
+			}
+		}
+		if dd.Humidity != nil && homecoachReports(device.DataType, homecoachDataHumidity) {
+			sendMetric(c.log, ch, homecoachHumidityDesc, prometheus.GaugeValue, float64(*dd.Humidity), labels...)
+		}
+		if dd.CO2 != nil && homecoachReports(device.DataType, homecoachDataCO2) {
+			sendMetric(c.log, ch, homecoachCO2Desc, prometheus.GaugeValue, float64(*dd.CO2), labels...)
+		}
+		if dd.Noise != nil && homecoachReports(device.DataType, homecoachDataNoise) {
+			sendMetric(c.log, ch, homecoachNoiseDesc, prometheus.GaugeValue, float64(*dd.Noise), labels...)
+		}
+		if dd.Pressure != nil && homecoachReports(device.DataType, homecoachDataPressure) {
+			sendMetric(c.log, ch, homecoachPressureDesc, prometheus.GaugeValue, float64(*dd.Pressure), labels...)
+			if units.pressure {
+				sendMetric(c.log, ch, homecoachPressureInHgDesc, prometheus.GaugeValue, mbarToInHg(float64(*dd.Pressure)), labels...)
+			}
+		}
+		if dd.AbsolutePressure != nil && homecoachReports(device.DataType, homecoachDataPressure) {
+			sendMetric(c.log, ch, homecoachAbsolutePressureDesc, prometheus.GaugeValue, float64(*dd.AbsolutePressure), labels...)
+		}
+		if dd.HealthIndex != nil && homecoachReports(device.DataType, homecoachDataHealthIndex) {
+			sendMetric(c.log, ch, homecoachHealthIndexDesc, prometheus.GaugeValue, float64(*dd.HealthIndex), labels...)
+		}
+		sendMetric(c.log, ch, homecoachWifiDesc, prometheus.GaugeValue, float64(device.WifiStatus), labels...)
 
-	c.cacheLock.Lock()
-	c.cacheTimestamp = now
-	c.cachedData = data
-	c.cacheLock.Unlock()
+		// The dates are missing if the device has not reported a minimum and maximum yet.
+		if dd.MinTemp != nil && dd.DateMinTemp != nil {
+			sendMetric(c.log, ch, homecoachMinTempDesc, prometheus.GaugeValue, float64(*dd.MinTemp), labels...)
+			sendMetric(c.log, ch, homecoachMinTempTimeDesc, prometheus.GaugeValue, float64(*dd.DateMinTemp), labels...)
+		}
+		if dd.MaxTemp != nil && dd.DateMaxTemp != nil {
+			sendMetric(c.log, ch, homecoachMaxTempDesc, prometheus.GaugeValue, float64(*dd.MaxTemp), labels...)
+			sendMetric(c.log, ch, homecoachMaxTempTimeDesc, prometheus.GaugeValue, float64(*dd.DateMaxTemp), labels...)
+		}
+	}
 }
 
 type HomecoachResponse struct {
 	Body struct {
-		Devices []struct {
-			ID              string   `json:"_id"`
-			DateSetup       int64    `json:"date_setup"`
-			LastSetup       int64    `json:"last_setup"`
-			Type            string   `json:"type"`
-			LastStatusStore int64    `json:"last_status_store"`
-			ModuleName      string   `json:"module_name"`
-			Firmware        int      `json:"firmware"`
-			LastUpgrade     int64    `json:"last_upgrade"`
-			WifiStatus      int      `json:"wifi_status"`
-			Reachable       bool     `json:"reachable"`
-			CO2Calibrating  bool     `json:"co2_calibrating"`
-			StationName     string   `json:"station_name"`
-			DataType        []string `json:"data_type"`
-			Place           struct {
-				Altitude int       `json:"altitude"`
-				City     string    `json:"city"`
-				Country  string    `json:"country"`
-				Timezone string    `json:"timezone"`
-				Location []float64 `json:"location"`
-			} `json:"place"`
-			DashboardData struct {
-				TimeUTC          int64   `json:"time_utc"`
-				Temperature      float32 `json:"Temperature"`
-				CO2              int32   `json:"CO2"`
-				Humidity         int32   `json:"Humidity"`
-				Noise            int32   `json:"Noise"`
-				Pressure         float32 `json:"Pressure"`
-				AbsolutePressure float32 `json:"AbsolutePressure"`
-				HealthIndex      int32   `json:"health_idx"`
-				MinTemp          float32 `json:"min_temp"`
-				MaxTemp          float32 `json:"max_temp"`
-				DateMaxTemp      int64   `json:"date_max_temp"`
-				DateMinTemp      int64   `json:"date_min_temp"`
-			} `json:"dashboard_data"`
-			Name     string `json:"name"`
-			ReadOnly bool   `json:"read_only"`
-		} `json:"devices"`
-		User struct {
-			Mail           string `json:"mail"`
-			Administrative struct {
-				Lang         string `json:"lang"`
-				RegLocale    string `json:"reg_locale"`
-				Country      string `json:"country"`
-				Unit         int    `json:"unit"`
-				Windunit     int    `json:"windunit"`
-				Pressureunit int    `json:"pressureunit"`
-				FeelLikeAlgo int    `json:"feel_like_algo"`
-			} `json:"administrative"`
+		Devices []HomecoachDevice `json:"devices"`
+		User    struct {
+			Mail           string         `json:"mail"`
+			Administrative Administrative `json:"administrative"`
 		} `json:"user"`
 	} `json:"body"`
 }
 
+// HomecoachDevice is a single HomeCoach device.
+type HomecoachDevice struct {
+	ID              string                  `json:"_id"`
+	DateSetup       int64                   `json:"date_setup"`
+	LastSetup       int64                   `json:"last_setup"`
+	Type            string                  `json:"type"`
+	LastStatusStore int64                   `json:"last_status_store"`
+	ModuleName      string                  `json:"module_name"`
+	Firmware        int                     `json:"firmware"`
+	LastUpgrade     int64                   `json:"last_upgrade"`
+	WifiStatus      int                     `json:"wifi_status"`
+	Reachable       bool                    `json:"reachable"`
+	CO2Calibrating  bool                    `json:"co2_calibrating"`
+	StationName     string                  `json:"station_name"`
+	DataType        []string                `json:"data_type"`
+	Place           Place                   `json:"place"`
+	DashboardData   *HomecoachDashboardData `json:"dashboard_data"`
+	Name            string                  `json:"name"`
+	ReadOnly        bool                    `json:"read_only"`
+}
+
+// HomecoachDashboardData contains the latest measurements of a HomeCoach device.
+// Values not reported by the device are nil.
+type HomecoachDashboardData struct {
+	TimeUTC          *int64   `json:"time_utc"`
+	Temperature      *float32 `json:"Temperature"`
+	CO2              *int32   `json:"CO2"`
+	Humidity         *int32   `json:"Humidity"`
+	Noise            *int32   `json:"Noise"`
+	Pressure         *float32 `json:"Pressure"`
+	AbsolutePressure *float32 `json:"AbsolutePressure"`
+	HealthIndex      *int32   `json:"health_idx"`
+	MinTemp          *float32 `json:"min_temp"`
+	MaxTemp          *float32 `json:"max_temp"`
+	DateMaxTemp      *int64   `json:"date_max_temp"`
+	DateMinTemp      *int64   `json:"date_min_temp"`
+}
+
+// Data types reported by HomeCoach devices in data_type.
+const (
+	homecoachDataTemperature = "Temperature"
+	homecoachDataCO2         = "CO2"
+	homecoachDataHumidity    = "Humidity"
+	homecoachDataNoise       = "Noise"
+	homecoachDataPressure    = "Pressure"
+	homecoachDataHealthIndex = "health_idx"
+)
+
+// homecoachReports checks whether a device reports the data type. Devices without a list of data types
+// are assumed to report all of them.
+func homecoachReports(dataTypes []string, dataType string) bool {
+	return len(dataTypes) == 0 || slices.Contains(dataTypes, dataType)
+}
+
 func FetchHomecoachData(client *http.Client) (*HomecoachResponse, error) {
 	req, err := http.NewRequest(http.MethodGet, "https://api.netatmo.com/api/gethomecoachsdata", nil)
 	if err != nil {
@@ -272,11 +369,12 @@ func FetchHomecoachData(client *http.Client) (*HomecoachResponse, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("gethomecoachsdata request failed: status %s", resp.Status)
+		return nil, fmt.Errorf("gethomecoachsdata request failed: %w", api.ResponseError(resp))
 	}
 
 	var result HomecoachResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		api.ReportDecodeError(resp)
 		return nil, fmt.Errorf("decoding gethomecoachsdata response: %w", err)
 	}
 
@@ -284,16 +382,12 @@ func FetchHomecoachData(client *http.Client) (*HomecoachResponse, error) {
 }
 
 // NewHomecoachReadFunction creates a reader function for HomeCoach data
-func NewHomecoachReadFunction(getCurrentToken func() (*oauth2.Token, error)) HomecoachReadFunction {
+func NewHomecoachReadFunction(getCurrentToken func() (*oauth2.Token, error), apiClient *http.Client) HomecoachReadFunction {
 	return func() (*HomecoachResponse, error) {
-		token, err := getCurrentToken()
+		httpClient, err := api.AuthenticatedClient(getCurrentToken, apiClient)
 		if err != nil {
-			return nil, fmt.Errorf("getting token: %w", err)
-		}
-		if token == nil || !token.Valid() {
-			return nil, fmt.Errorf("token not available or invalid")
+			return nil, err
 		}
-		httpClient := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(token))
 		return FetchHomecoachData(httpClient)
 	}
 }
@@ -0,0 +1,336 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+const testHomecoachJSON = `{
+	"body": {
+		"devices": [
+			{
+				"_id": "70:ee:50:00:00:10",
+				"type": "NHC",
+				"station_name": "Bedroom",
+				"wifi_status": 58,
+				"reachable": true,
+				"last_status_store": 1700000050,
+				"dashboard_data": {
+					"time_utc": 1700000000,
+					"Temperature": 20.5,
+					"CO2": 800,
+					"Humidity": 50,
+					"Noise": 32,
+					"Pressure": 1012.4,
+					"AbsolutePressure": 950.25,
+					"health_idx": 1,
+					"min_temp": 19.25,
+					"max_temp": 21.25,
+					"date_min_temp": 1699941600,
+					"date_max_temp": 1699970400
+				}
+			}
+		]
+	}
+}`
+
+func newTestHomecoachStore(t *testing.T, log logrus.FieldLogger) *Store {
+	t.Helper()
+
+	var homecoach HomecoachResponse
+	if err := json.Unmarshal([]byte(testHomecoachJSON), &homecoach); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	homecoachReader := func() (*HomecoachResponse, error) {
+		return &homecoach, nil
+	}
+
+	store := NewStore(log, nil, homecoachReader, nil, time.Hour)
+	store.RefreshHomecoach()
+
+	return store
+}
+
+func TestHomecoachMinMaxTemperature(t *testing.T) {
+	log := logrus.New()
+	store := newTestHomecoachStore(t, log)
+
+	wantV1 := `# HELP netatmo_homecoach_absolute_pressure Netatmo Home Coach measured absolute pressure in mb, not reduced to sea level.
+# TYPE netatmo_homecoach_absolute_pressure gauge
+netatmo_homecoach_absolute_pressure{device_id="70:ee:50:00:00:10",device_name="Bedroom"} 950.25
+# HELP netatmo_homecoach_max_temperature Netatmo Home Coach maximum temperature of the current day in degrees Celsius.
+# TYPE netatmo_homecoach_max_temperature gauge
+netatmo_homecoach_max_temperature{device_id="70:ee:50:00:00:10",device_name="Bedroom"} 21.25
+# HELP netatmo_homecoach_max_temperature_time Time of the Netatmo Home Coach maximum temperature of the current day.
+# TYPE netatmo_homecoach_max_temperature_time gauge
+netatmo_homecoach_max_temperature_time{device_id="70:ee:50:00:00:10",device_name="Bedroom"} 1.6999704e+09
+# HELP netatmo_homecoach_min_temperature Netatmo Home Coach minimum temperature of the current day in degrees Celsius.
+# TYPE netatmo_homecoach_min_temperature gauge
+netatmo_homecoach_min_temperature{device_id="70:ee:50:00:00:10",device_name="Bedroom"} 19.25
+# HELP netatmo_homecoach_min_temperature_time Time of the Netatmo Home Coach minimum temperature of the current day.
+# TYPE netatmo_homecoach_min_temperature_time gauge
+netatmo_homecoach_min_temperature_time{device_id="70:ee:50:00:00:10",device_name="Bedroom"} 1.6999416e+09
+`
+
+	metricNamesV1 := []string{
+		"netatmo_homecoach_absolute_pressure",
+		"netatmo_homecoach_max_temperature",
+		"netatmo_homecoach_max_temperature_time",
+		"netatmo_homecoach_min_temperature",
+		"netatmo_homecoach_min_temperature_time",
+	}
+
+	collectorV1 := NewHomecoachCollector(log, store, time.Hour, StaleDrop, UnitsMetric)
+	collectorV1.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	if err := testutil.CollectAndCompare(collectorV1, strings.NewReader(wantV1), metricNamesV1...); err != nil {
+		t.Errorf("V1: %s", err)
+	}
+
+	wantV2 := `# HELP netatmo_sensor_max_temperature_celsius Maximum temperature of the current day in celsius
+# TYPE netatmo_sensor_max_temperature_celsius gauge
+netatmo_sensor_max_temperature_celsius{device_class="homecoach",device_id="70:ee:50:00:00:10",home="",module="id-70:ee:50:00:00:10",station="Bedroom"} 21.25
+# HELP netatmo_sensor_min_temperature_celsius Minimum temperature of the current day in celsius
+# TYPE netatmo_sensor_min_temperature_celsius gauge
+netatmo_sensor_min_temperature_celsius{device_class="homecoach",device_id="70:ee:50:00:00:10",home="",module="id-70:ee:50:00:00:10",station="Bedroom"} 19.25
+`
+
+	metricNamesV2 := []string{
+		"netatmo_sensor_max_temperature_celsius",
+		"netatmo_sensor_min_temperature_celsius",
+	}
+
+	collectorV2 := UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsMetric)
+	collectorV2.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	if err := testutil.CollectAndCompare(collectorV2, strings.NewReader(wantV2), metricNamesV2...); err != nil {
+		t.Errorf("V2: %s", err)
+	}
+}
+
+func TestHomecoachReachability(t *testing.T) {
+	log := logrus.New()
+	store := newTestHomecoachStore(t, log)
+
+	want := `# HELP netatmo_sensor_data_age_seconds Age of the latest measurement in seconds, also exported for stale data
+# TYPE netatmo_sensor_data_age_seconds gauge
+netatmo_sensor_data_age_seconds{device_class="homecoach",device_id="70:ee:50:00:00:10",home="",module="id-70:ee:50:00:00:10",station="Bedroom"} 300
+# HELP netatmo_sensor_last_status_store_time Time the last status of the station was stored by the Netatmo cloud
+# TYPE netatmo_sensor_last_status_store_time gauge
+netatmo_sensor_last_status_store_time{device_class="homecoach",device_id="70:ee:50:00:00:10",home="",module="id-70:ee:50:00:00:10",station="Bedroom"} 1.70000005e+09
+# HELP netatmo_sensor_reachable One if the device or module is reachable by the Netatmo cloud
+# TYPE netatmo_sensor_reachable gauge
+netatmo_sensor_reachable{device_class="homecoach",device_id="70:ee:50:00:00:10",home="",module="id-70:ee:50:00:00:10",station="Bedroom"} 1
+`
+
+	metricNames := []string{
+		"netatmo_sensor_data_age_seconds",
+		"netatmo_sensor_last_status_store_time",
+		"netatmo_sensor_reachable",
+	}
+
+	collector := UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsMetric)
+	collector.clock = func() time.Time {
+		return time.Unix(1700000300, 0)
+	}
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHomecoachStalePolicy(t *testing.T) {
+	log := logrus.New()
+	store := newTestHomecoachStore(t, log)
+
+	wantV1 := `# HELP netatmo_homecoach_stale One if the latest Netatmo Home Coach measurement is older than the stale threshold.
+# TYPE netatmo_homecoach_stale gauge
+netatmo_homecoach_stale{device_id="70:ee:50:00:00:10",device_name="Bedroom"} 1
+# HELP netatmo_homecoach_temperature Netatmo Home Coach measured temperature in degrees Celsius.
+# TYPE netatmo_homecoach_temperature gauge
+netatmo_homecoach_temperature{device_id="70:ee:50:00:00:10",device_name="Bedroom"} 20.5
+`
+
+	collectorV1 := NewHomecoachCollector(log, store, time.Hour, StaleMark, UnitsMetric)
+	collectorV1.clock = func() time.Time {
+		return testWeatherTime.Add(2 * time.Hour)
+	}
+
+	if err := testutil.CollectAndCompare(collectorV1, strings.NewReader(wantV1), "netatmo_homecoach_stale", "netatmo_homecoach_temperature"); err != nil {
+		t.Errorf("V1: %s", err)
+	}
+
+	// Dropping stale devices removes their measurements.
+	collectorV2 := UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsMetric)
+	collectorV2.clock = func() time.Time {
+		return testWeatherTime.Add(2 * time.Hour)
+	}
+
+	if err := testutil.CollectAndCompare(collectorV2, strings.NewReader(""), "netatmo_sensor_stale", "netatmo_sensor_temperature_celsius"); err != nil {
+		t.Errorf("V2: %s", err)
+	}
+}
+
+func TestHomecoachMissingData(t *testing.T) {
+	log := logrus.New()
+
+	// The first device only reports some data types, the second one is unreachable and has no data.
+	const data = `{
+	"body": {
+		"devices": [
+			{
+				"_id": "70:ee:50:00:00:11",
+				"type": "NHC",
+				"station_name": "Office",
+				"reachable": true,
+				"data_type": ["Temperature", "Humidity"],
+				"dashboard_data": {"time_utc": 1700000000, "Temperature": 21.5, "Humidity": 40, "CO2": 0}
+			},
+			{
+				"_id": "70:ee:50:00:00:12",
+				"type": "NHC",
+				"station_name": "Basement",
+				"reachable": false,
+				"data_type": ["Temperature", "CO2", "Humidity", "Noise", "Pressure", "health_idx"]
+			}
+		]
+	}
+}`
+
+	var homecoach HomecoachResponse
+	if err := json.Unmarshal([]byte(data), &homecoach); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	store := NewStore(log, nil, func() (*HomecoachResponse, error) {
+		return &homecoach, nil
+	}, nil, time.Hour)
+	store.RefreshHomecoach()
+
+	wantV1 := `# HELP netatmo_homecoach_humidity Netatmo Home Coach measured humidity in percent.
+# TYPE netatmo_homecoach_humidity gauge
+netatmo_homecoach_humidity{device_id="70:ee:50:00:00:11",device_name="Office"} 40
+# HELP netatmo_homecoach_temperature Netatmo Home Coach measured temperature in degrees Celsius.
+# TYPE netatmo_homecoach_temperature gauge
+netatmo_homecoach_temperature{device_id="70:ee:50:00:00:11",device_name="Office"} 21.5
+`
+
+	collectorV1 := NewHomecoachCollector(log, store, time.Hour, StaleDrop, UnitsMetric)
+	collectorV1.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	metricNamesV1 := []string{
+		"netatmo_homecoach_co2",
+		"netatmo_homecoach_humidity",
+		"netatmo_homecoach_temperature",
+	}
+	if err := testutil.CollectAndCompare(collectorV1, strings.NewReader(wantV1), metricNamesV1...); err != nil {
+		t.Errorf("V1: %s", err)
+	}
+
+	wantV2 := `# HELP netatmo_sensor_reachable One if the device or module is reachable by the Netatmo cloud
+# TYPE netatmo_sensor_reachable gauge
+netatmo_sensor_reachable{device_class="homecoach",device_id="70:ee:50:00:00:11",home="",module="id-70:ee:50:00:00:11",station="Office"} 1
+netatmo_sensor_reachable{device_class="homecoach",device_id="70:ee:50:00:00:12",home="",module="id-70:ee:50:00:00:12",station="Basement"} 0
+# HELP netatmo_sensor_temperature_celsius Temperature measurement in celsius
+# TYPE netatmo_sensor_temperature_celsius gauge
+netatmo_sensor_temperature_celsius{device_class="homecoach",device_id="70:ee:50:00:00:11",home="",module="id-70:ee:50:00:00:11",station="Office"} 21.5
+`
+
+	collectorV2 := UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsMetric)
+	collectorV2.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	metricNamesV2 := []string{
+		"netatmo_sensor_co2_ppm",
+		"netatmo_sensor_reachable",
+		"netatmo_sensor_temperature_celsius",
+	}
+	if err := testutil.CollectAndCompare(collectorV2, strings.NewReader(wantV2), metricNamesV2...); err != nil {
+		t.Errorf("V2: %s", err)
+	}
+}
+
+func TestHomecoachLabels(t *testing.T) {
+	log := logrus.New()
+
+	const data = `{
+	"body": {
+		"devices": [
+			{
+				"_id": "70:ee:50:00:00:11",
+				"type": "NHC",
+				"station_name": "Office",
+				"module_name": "Desk",
+				"reachable": true
+			},
+			{
+				"_id": "70:ee:50:00:00:12",
+				"type": "NHC",
+				"station_name": "Bedroom",
+				"name": "Bed",
+				"reachable": true
+			},
+			{
+				"_id": "70:ee:50:00:00:13",
+				"type": "NHC",
+				"station_name": "Cellar",
+				"reachable": true
+			}
+		]
+	}
+}`
+
+	var homecoach HomecoachResponse
+	if err := json.Unmarshal([]byte(data), &homecoach); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	homes := &HomesResponse{
+		Homes: []Home{
+			{
+				ID:   "home1",
+				Name: "Home",
+				Modules: []Module{
+					{ID: "70:ee:50:00:00:11", Type: "NHC", Name: "Office"},
+					{ID: "70:ee:50:00:00:12", Type: "NHC", Name: "Bedroom"},
+				},
+			},
+		},
+	}
+
+	store := NewStore(log, nil, func() (*HomecoachResponse, error) {
+		return &homecoach, nil
+	}, func() (*HomesResponse, error) {
+		return homes, nil
+	}, time.Hour)
+	store.SetHomecoachHomes(map[string]string{
+		"70:ee:50:00:00:12": "Holiday Home",
+	})
+	store.RefreshHomecoach()
+	store.RefreshHomes()
+
+	want := `# HELP netatmo_sensor_reachable One if the device or module is reachable by the Netatmo cloud
+# TYPE netatmo_sensor_reachable gauge
+netatmo_sensor_reachable{device_class="homecoach",device_id="70:ee:50:00:00:11",home="Home",module="Desk",station="Office"} 1
+netatmo_sensor_reachable{device_class="homecoach",device_id="70:ee:50:00:00:12",home="Holiday Home",module="Bed",station="Bedroom"} 1
+netatmo_sensor_reachable{device_class="homecoach",device_id="70:ee:50:00:00:13",home="",module="id-70:ee:50:00:00:13",station="Cellar"} 1
+`
+
+	collector := UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsMetric)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), "netatmo_sensor_reachable"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,293 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/marc825/netatmo-exporter/v2/internal/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// DeviceClassHomeControl is the device class of Legrand Home+Control modules.
+const DeviceClassHomeControl = "homecontrol"
+
+const (
+	measureURL = "https://api.netatmo.com/api/getmeasure"
+
+	// energyMeasureStep is the scale used for reading the energy sums. Sums are only read once the
+	// interval is complete, so the counters lag behind by up to one step.
+	energyMeasureStep  = 5 * time.Minute
+	energyMeasureScale = "5min"
+)
+
+var (
+	homeControlPrefix = prefix + "homecontrol_"
+
+	// homeControlModuleTypes contains the module types which belong to Legrand Home+Control.
+	homeControlModuleTypes = map[string]bool{
+		"NLG":  true, // Gateway
+		"NLP":  true, // Smart plug
+		"NLPM": true, // Mobile plug
+		"NLPO": true, // Contactor
+		"NLPT": true, // Teleruptor
+		"NLPC": true, // Energy meter
+		"NLPD": true, // Dry contact
+		"NLC":  true, // Cable outlet
+		"NLL":  true, // Light switch
+		"NLF":  true, // Dimmer
+		"NLFN": true, // Dimmer without neutral
+		"NLIS": true, // Double light switch
+		"NLM":  true, // Micro module
+	}
+
+	// Home+Control collector status metrics
+	homeControlUpDesc               = prometheus.NewDesc(homeControlPrefix+"up", "Zero if there was an error during the last refresh try.", nil, nil)
+	homeControlRefreshIntervalDesc  = prometheus.NewDesc(homeControlPrefix+"refresh_interval_seconds", "Contains the configured refresh interval in seconds. This is provided as a convenience for calculations with the cache update time.", nil, nil)
+	homeControlRefreshTimestampDesc = prometheus.NewDesc(homeControlPrefix+"last_refresh_time", "Contains the time of the last refresh try, successful or not.", nil, nil)
+	homeControlRefreshDurationDesc  = prometheus.NewDesc(homeControlPrefix+"last_refresh_duration_seconds", "Contains the time it took for the last refresh to complete, even if it was unsuccessful.", nil, nil)
+	homeControlCacheTimestampDesc   = prometheus.NewDesc(homeControlPrefix+"cache_updated_time", "Contains the time of the cached data.", nil, nil)
+
+	// Module metrics
+	homeControlPowerDesc  = prometheus.NewDesc(homeControlPrefix+"power_watts", "Current power consumption in watts", v2LabelNames, nil)
+	homeControlEnergyDesc = prometheus.NewDesc(homeControlPrefix+"energy_watthours_total", "Energy consumed since the exporter started in watt hours", v2LabelNames, nil)
+	homeControlOnDesc     = prometheus.NewDesc(homeControlPrefix+"on", "One if the module is switched on", v2LabelNames, nil)
+	homeControlRFDesc     = prometheus.NewDesc(homeControlPrefix+"rf_signal_strength", "RF signal strength (90: lowest, 60: highest)", v2LabelNames, nil)
+	homeControlWifiDesc   = prometheus.NewDesc(homeControlPrefix+"wifi_signal_strength", "Wifi signal strength (86: bad, 71: avg, 56: good)", v2LabelNames, nil)
+)
+
+// EnergyMeasureFunction reads the energy sums of a module between begin and end. The result contains the
+// energy in watt hours by start of the measurement interval.
+type EnergyMeasureFunction func(bridgeID, moduleID string, begin, end time.Time) (map[time.Time]float64, error)
+
+// energyMeter accumulates the energy sums of a single module.
+type energyMeter struct {
+	total float64
+	// last is the start of the last interval included in total.
+	last time.Time
+}
+
+// HomeControlCollector is a Prometheus collector for Legrand Home+Control plugs, switches and energy meters.
+// It uses the unified V2 labels with device_class "homecontrol".
+type HomeControlCollector struct {
+	log           logrus.FieldLogger
+	store         *Store
+	readEnergySum EnergyMeasureFunction
+
+	lock   sync.Mutex
+	meters map[string]*energyMeter
+}
+
+// NewHomeControlCollector creates a HomeControlCollector which reads the homes from the store.
+// The energy counters are updated by OnRefresh, which needs to be added as hook to the store.
+func NewHomeControlCollector(log logrus.FieldLogger, store *Store, readEnergySum EnergyMeasureFunction) *HomeControlCollector {
+	return &HomeControlCollector{
+		log:           log,
+		store:         store,
+		readEnergySum: readEnergySum,
+		meters:        make(map[string]*energyMeter),
+	}
+}
+
+// OnRefresh is a RefreshHook which adds the energy used since the last refresh to the counters.
+func (c *HomeControlCollector) OnRefresh(name string, _, current time.Time) {
+	if name != endpointHomes {
+		return
+	}
+
+	data := c.store.Homes().Data
+	if data == nil {
+		return
+	}
+
+	end := current.Add(-energyMeasureStep)
+	for _, home := range data.Homes {
+		moduleStatus := home.moduleStatus()
+		for _, module := range home.Modules {
+			status, ok := moduleStatus[module.ID]
+			if !ok || !homeControlModuleTypes[module.Type] || status.Power == nil {
+				continue
+			}
+
+			c.updateMeter(module, end)
+		}
+	}
+}
+
+func (c *HomeControlCollector) updateMeter(module Module, end time.Time) {
+	c.lock.Lock()
+	meter, ok := c.meters[module.ID]
+	if !ok {
+		// The counter starts at zero, only energy used after startup is counted.
+		c.meters[module.ID] = &energyMeter{last: end}
+		c.lock.Unlock()
+		return
+	}
+	begin := meter.last
+	c.lock.Unlock()
+
+	if !end.After(begin) {
+		return
+	}
+
+	bridgeID := module.Bridge
+	if bridgeID == "" {
+		bridgeID = module.ID
+	}
+
+	sums, err := c.readEnergySum(bridgeID, module.ID, begin, end)
+	if err != nil {
+		c.log.Errorf("Error reading energy of %s: %s", module.ID, err)
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for ts, value := range sums {
+		if !ts.After(begin) || ts.After(end) {
+			continue
+		}
+
+		meter.total += value
+		if ts.After(meter.last) {
+			meter.last = ts
+		}
+	}
+}
+
+func (c *HomeControlCollector) energyTotal(moduleID string) (float64, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	meter, ok := c.meters[moduleID]
+	if !ok {
+		return 0, false
+	}
+
+	return meter.total, true
+}
+
+func (c *HomeControlCollector) Describe(ch chan<- *prometheus.Desc) {
+	// Status metrics
+	ch <- homeControlUpDesc
+	ch <- homeControlRefreshIntervalDesc
+	ch <- homeControlRefreshTimestampDesc
+	ch <- homeControlRefreshDurationDesc
+	ch <- homeControlCacheTimestampDesc
+
+	// Data metrics
+	ch <- homeControlPowerDesc
+	ch <- homeControlEnergyDesc
+	ch <- homeControlOnDesc
+	ch <- homeControlRFDesc
+	ch <- homeControlWifiDesc
+}
+
+func (c *HomeControlCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.store.Homes()
+
+	upValue := 1.0
+	if !snapshot.Up() {
+		upValue = 0
+	}
+
+	sendMetric(c.log, ch, homeControlUpDesc, prometheus.GaugeValue, upValue)
+	sendMetric(c.log, ch, homeControlRefreshIntervalDesc, prometheus.GaugeValue, c.store.RefreshInterval().Seconds())
+	sendMetric(c.log, ch, homeControlRefreshTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.LastRefresh))
+	sendMetric(c.log, ch, homeControlRefreshDurationDesc, prometheus.GaugeValue, snapshot.LastRefreshDuration.Seconds())
+	sendMetric(c.log, ch, homeControlCacheTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.CacheTimestamp))
+
+	if snapshot.Data == nil {
+		return
+	}
+
+	for _, home := range snapshot.Data.Homes {
+		c.collectHome(ch, home)
+	}
+}
+
+func (c *HomeControlCollector) collectHome(ch chan<- prometheus.Metric, home Home) {
+	moduleStatus := home.moduleStatus()
+	moduleNames := home.moduleNames()
+
+	for _, module := range home.Modules {
+		if !homeControlModuleTypes[module.Type] {
+			continue
+		}
+
+		status, ok := moduleStatus[module.ID]
+		if !ok {
+			continue
+		}
+
+		labels := home.moduleLabels(DeviceClassHomeControl, module, moduleNames)
+
+		if status.Power != nil {
+			sendMetric(c.log, ch, homeControlPowerDesc, prometheus.GaugeValue, *status.Power, labels...)
+		}
+		if total, ok := c.energyTotal(module.ID); ok {
+			sendMetric(c.log, ch, homeControlEnergyDesc, prometheus.CounterValue, total, labels...)
+		}
+		if status.On != nil {
+			sendMetric(c.log, ch, homeControlOnDesc, prometheus.GaugeValue, boolValue(*status.On), labels...)
+		}
+		if status.RFStrength != nil {
+			sendMetric(c.log, ch, homeControlRFDesc, prometheus.GaugeValue, float64(*status.RFStrength), labels...)
+		}
+		if status.WifiStrength != nil {
+			sendMetric(c.log, ch, homeControlWifiDesc, prometheus.GaugeValue, float64(*status.WifiStrength), labels...)
+		}
+	}
+}
+
+// FetchEnergySum reads the energy sums of a module using getmeasure.
+func FetchEnergySum(client *http.Client, bridgeID, moduleID string, begin, end time.Time) (map[time.Time]float64, error) {
+	query := url.Values{}
+	query.Set("device_id", bridgeID)
+	query.Set("module_id", moduleID)
+	query.Set("scale", energyMeasureScale)
+	query.Set("type", "sum_energy_elec")
+	query.Set("date_begin", strconv.FormatInt(begin.Unix(), 10))
+	query.Set("date_end", strconv.FormatInt(end.Unix(), 10))
+	query.Set("optimize", "false")
+
+	var data struct {
+		Body map[string][]*float64 `json:"body"`
+	}
+	if err := getJSON(client, measureURL+"?"+query.Encode(), &data); err != nil {
+		return nil, err
+	}
+
+	result := make(map[time.Time]float64, len(data.Body))
+	for key, values := range data.Body {
+		ts, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp in getmeasure response: %q", key)
+		}
+
+		if len(values) == 0 || values[0] == nil {
+			continue
+		}
+
+		result[time.Unix(ts, 0)] = *values[0]
+	}
+
+	return result, nil
+}
+
+// NewEnergyMeasureFunction creates a function reading the energy sums of Home+Control modules.
+func NewEnergyMeasureFunction(getCurrentToken func() (*oauth2.Token, error), apiClient *http.Client) EnergyMeasureFunction {
+	return func(bridgeID, moduleID string, begin, end time.Time) (map[time.Time]float64, error) {
+		httpClient, err := api.AuthenticatedClient(getCurrentToken, apiClient)
+		if err != nil {
+			return nil, err
+		}
+		return FetchEnergySum(httpClient, bridgeID, moduleID, begin, end)
+	}
+}
@@ -0,0 +1,86 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestHomeControlCollector(t *testing.T) {
+	on := true
+	power := 120.0
+	homes := &HomesResponse{
+		Homes: []Home{
+			{
+				ID:   "home1",
+				Name: "Home",
+				Modules: []Module{
+					{ID: "gateway", Type: "NLG", Name: "Gateway"},
+					{ID: "plug", Type: "NLP", Name: "Washing machine", Bridge: "gateway"},
+				},
+				Status: HomeStatus{
+					Modules: []ModuleStatus{
+						{ID: "gateway", Type: "NLG"},
+						{ID: "plug", Type: "NLP", On: &on, Power: &power},
+					},
+				},
+			},
+		},
+	}
+
+	start := time.Unix(1700000000, 0)
+	now := start
+	var requests []string
+	readEnergySum := func(bridgeID, moduleID string, begin, end time.Time) (map[time.Time]float64, error) {
+		requests = append(requests, bridgeID+"/"+moduleID)
+		return map[time.Time]float64{
+			begin:                      100, // already counted
+			begin.Add(time.Minute):     5,
+			begin.Add(5 * time.Minute): 7,
+			end.Add(time.Minute):       11, // not complete yet
+		}, nil
+	}
+
+	log := logrus.New()
+	store := NewStore(log, nil, nil, func() (*HomesResponse, error) {
+		return homes, nil
+	}, time.Hour)
+	store.clock = func() time.Time {
+		return now
+	}
+
+	collector := NewHomeControlCollector(log, store, readEnergySum)
+	store.OnRefresh(collector.OnRefresh)
+
+	store.RefreshHomes()
+	now = start.Add(10 * time.Minute)
+	store.RefreshHomes()
+
+	if len(requests) != 1 || requests[0] != "gateway/plug" {
+		t.Errorf("got energy requests %v, want [gateway/plug]", requests)
+	}
+
+	want := `# HELP netatmo_homecontrol_energy_watthours_total Energy consumed since the exporter started in watt hours
+# TYPE netatmo_homecontrol_energy_watthours_total counter
+netatmo_homecontrol_energy_watthours_total{device_class="homecontrol",device_id="plug",home="Home",module="Washing machine",station="Gateway"} 12
+# HELP netatmo_homecontrol_on One if the module is switched on
+# TYPE netatmo_homecontrol_on gauge
+netatmo_homecontrol_on{device_class="homecontrol",device_id="plug",home="Home",module="Washing machine",station="Gateway"} 1
+# HELP netatmo_homecontrol_power_watts Current power consumption in watts
+# TYPE netatmo_homecontrol_power_watts gauge
+netatmo_homecontrol_power_watts{device_class="homecontrol",device_id="plug",home="Home",module="Washing machine",station="Gateway"} 120
+`
+
+	metricNames := []string{
+		"netatmo_homecontrol_energy_watthours_total",
+		"netatmo_homecontrol_on",
+		"netatmo_homecontrol_power_watts",
+	}
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,263 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/marc825/netatmo-exporter/v2/internal/api"
+	"golang.org/x/oauth2"
+)
+
+const (
+	homesDataURL  = "https://api.netatmo.com/api/homesdata"
+	homeStatusURL = "https://api.netatmo.com/api/homestatus"
+	eventsURL     = "https://api.netatmo.com/api/getevents"
+
+	// endpointHomes is the name of the store endpoint containing the homesdata and homestatus data.
+	endpointHomes = "homes"
+)
+
+// HomesReadFunction defines the interface for reading the homes from the Netatmo API.
+type HomesReadFunction func() (*HomesResponse, error)
+
+// HomesResponse contains the topology of all homes read from homesdata together with the current status
+// of every home read from homestatus. The devices contained depend on the scopes of the token.
+type HomesResponse struct {
+	Homes []Home `json:"homes"`
+}
+
+// Home is a single home with its rooms and modules.
+type Home struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	Rooms   []Room     `json:"rooms"`
+	Modules []Module   `json:"modules"`
+	Status  HomeStatus `json:"status"`
+	Events  []Event    `json:"events,omitempty"`
+}
+
+// Room is a room of a home as returned by homesdata.
+type Room struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	ModuleIDs []string `json:"module_ids"`
+}
+
+// Module is a module of a home as returned by homesdata.
+type Module struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	RoomID    string `json:"room_id"`
+	Bridge    string `json:"bridge"`
+	SetupDate int64  `json:"setup_date"`
+}
+
+// HomeStatus is the current status of a home as returned by homestatus.
+type HomeStatus struct {
+	Rooms   []RoomStatus   `json:"rooms"`
+	Modules []ModuleStatus `json:"modules"`
+}
+
+// RoomStatus is the current status of a room. Fields not reported for a room are nil.
+type RoomStatus struct {
+	ID                       string   `json:"id"`
+	Reachable                *bool    `json:"reachable"`
+	ThermMeasuredTemperature *float64 `json:"therm_measured_temperature"`
+	ThermSetpointTemperature *float64 `json:"therm_setpoint_temperature"`
+	ThermSetpointMode        string   `json:"therm_setpoint_mode"`
+	HeatingPowerRequest      *int32   `json:"heating_power_request"`
+	OpenWindow               *bool    `json:"open_window"`
+}
+
+// ModuleStatus is the current status of a module. Fields not reported for a module type are nil.
+type ModuleStatus struct {
+	ID               string   `json:"id"`
+	Type             string   `json:"type"`
+	Bridge           string   `json:"bridge"`
+	FirmwareRevision *int64   `json:"firmware_revision"`
+	Reachable        *bool    `json:"reachable"`
+	BatteryLevel     *int32   `json:"battery_level"`
+	BatteryState     string   `json:"battery_state"`
+	RFStrength       *int32   `json:"rf_strength"`
+	WifiStrength     *int32   `json:"wifi_strength"`
+	BoilerStatus     *bool    `json:"boiler_status"`
+	Monitoring       string   `json:"monitoring"`
+	SDStatus         *int32   `json:"sd_status"`
+	AlimStatus       *int32   `json:"alim_status"`
+	LightModeStatus  string   `json:"light_mode_status"`
+	Status           string   `json:"status"`
+	On               *bool    `json:"on"`
+	Power            *float64 `json:"power"`
+}
+
+// Event is a security event of a home as returned by getevents.
+type Event struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Time     int64  `json:"time"`
+	ModuleID string `json:"module_id"`
+	SubType  *int32 `json:"sub_type"`
+}
+
+// moduleNames returns the names of all modules of the home by ID.
+func (h Home) moduleNames() map[string]string {
+	names := make(map[string]string, len(h.Modules))
+	for _, m := range h.Modules {
+		names[m.ID] = weatherModuleName(m.Name, m.ID)
+	}
+
+	return names
+}
+
+// moduleLabels returns the V2 label values of a module. The station label contains the name of the
+// bridge the module is connected to, or the name of the module itself if it is not bridged.
+func (h Home) moduleLabels(class string, module Module, names map[string]string) []string {
+	stationName := names[module.ID]
+	if module.Bridge != "" {
+		stationName = names[module.Bridge]
+	}
+
+	return []string{class, module.ID, h.Name, names[module.ID], stationName}
+}
+
+// lastEvents returns the time of the latest event of every module by ID.
+func (h Home) lastEvents() map[string]int64 {
+	last := make(map[string]int64)
+	for _, e := range h.Events {
+		if e.Time > last[e.ModuleID] {
+			last[e.ModuleID] = e.Time
+		}
+	}
+
+	return last
+}
+
+// latestEvent returns the latest event of the module with one of the given types.
+func (h Home) latestEvent(moduleID string, types ...string) (Event, bool) {
+	var latest Event
+	found := false
+	for _, e := range h.Events {
+		if e.ModuleID != moduleID || (found && e.Time <= latest.Time) {
+			continue
+		}
+
+		for _, t := range types {
+			if e.Type == t {
+				latest = e
+				found = true
+				break
+			}
+		}
+	}
+
+	return latest, found
+}
+
+// moduleStatus returns the status of all modules of the home by ID.
+func (h Home) moduleStatus() map[string]ModuleStatus {
+	status := make(map[string]ModuleStatus, len(h.Status.Modules))
+	for _, m := range h.Status.Modules {
+		status[m.ID] = m
+	}
+
+	return status
+}
+
+// roomStatus returns the status of all rooms of the home by ID.
+func (h Home) roomStatus() map[string]RoomStatus {
+	status := make(map[string]RoomStatus, len(h.Status.Rooms))
+	for _, r := range h.Status.Rooms {
+		status[r.ID] = r
+	}
+
+	return status
+}
+
+// FetchHomesData reads the topology of all homes and the status of every home.
+// If readEvents is set, the latest security events of every home are read as well.
+func FetchHomesData(client *http.Client, readEvents bool) (*HomesResponse, error) {
+	var data struct {
+		Body HomesResponse `json:"body"`
+	}
+	if err := getJSON(client, homesDataURL, &data); err != nil {
+		return nil, err
+	}
+
+	for i, home := range data.Body.Homes {
+		var status struct {
+			Body struct {
+				Home HomeStatus `json:"home"`
+			} `json:"body"`
+		}
+
+		query := url.Values{}
+		query.Set("home_id", home.ID)
+		if err := getJSON(client, homeStatusURL+"?"+query.Encode(), &status); err != nil {
+			return nil, fmt.Errorf("home %s: %w", home.ID, err)
+		}
+
+		data.Body.Homes[i].Status = status.Body.Home
+
+		if !readEvents {
+			continue
+		}
+
+		var events struct {
+			Body struct {
+				Home struct {
+					Events []Event `json:"events"`
+				} `json:"home"`
+			} `json:"body"`
+		}
+
+		if err := getJSON(client, eventsURL+"?"+query.Encode(), &events); err != nil {
+			return nil, fmt.Errorf("home %s: %w", home.ID, err)
+		}
+
+		data.Body.Homes[i].Events = events.Body.Home.Events
+	}
+
+	return &data.Body, nil
+}
+
+// getJSON requests the URL and decodes the JSON response into result.
+func getJSON(client *http.Client, requestURL string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	name := path.Base(req.URL.Path)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing %s request: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s request failed: %w", name, api.ResponseError(resp))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		api.ReportDecodeError(resp)
+		return fmt.Errorf("decoding %s response: %w", name, err)
+	}
+
+	return nil
+}
+
+// NewHomesReadFunction creates a reader function for the homes.
+func NewHomesReadFunction(getCurrentToken func() (*oauth2.Token, error), apiClient *http.Client, readEvents bool) HomesReadFunction {
+	return func() (*HomesResponse, error) {
+		httpClient, err := api.AuthenticatedClient(getCurrentToken, apiClient)
+		if err != nil {
+			return nil, err
+		}
+		return FetchHomesData(httpClient, readEvents)
+	}
+}
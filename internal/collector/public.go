@@ -0,0 +1,309 @@
+package collector
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/marc825/netatmo-exporter/v2/internal/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// DeviceClassPublic is the device class of public weather stations.
+const DeviceClassPublic = "public"
+
+const (
+	publicDataURL = "https://api.netatmo.com/api/getpublicdata"
+
+	// endpointPublic is the name of the store endpoint containing the public weather stations.
+	endpointPublic = "public"
+)
+
+// BoundingBox is a geographic area defined by its south-west and north-east corners.
+type BoundingBox struct {
+	LatSW float64
+	LonSW float64
+	LatNE float64
+	LonNE float64
+}
+
+// PublicReadFunction defines the interface for reading the public weather stations from the Netatmo API.
+type PublicReadFunction func() (*PublicResponse, error)
+
+// PublicResponse contains the public weather stations in the configured area.
+type PublicResponse struct {
+	Stations []PublicStation `json:"stations"`
+}
+
+// PublicStation contains the latest measurements of a single public weather station.
+// Measurements not available for a station are nil.
+type PublicStation struct {
+	ID           string   `json:"id"`
+	City         string   `json:"city,omitempty"`
+	Latitude     float64  `json:"latitude"`
+	Longitude    float64  `json:"longitude"`
+	Altitude     float64  `json:"altitude"`
+	Temperature  *float64 `json:"temperature,omitempty"`
+	Humidity     *float64 `json:"humidity,omitempty"`
+	Pressure     *float64 `json:"pressure,omitempty"`
+	Rain         *float64 `json:"rain,omitempty"`
+	WindStrength *float64 `json:"wind_strength,omitempty"`
+}
+
+// publicMeasurement describes one measurement aggregated across the public stations.
+type publicMeasurement struct {
+	name        string
+	value       func(s PublicStation) *float64
+	areaDesc    *prometheus.Desc
+	stationDesc *prometheus.Desc
+}
+
+var (
+	publicPrefix = prefix + "public_"
+
+	publicAggregationLabelNames = []string{"aggregation"}
+	publicCountLabelNames       = []string{"measurement"}
+
+	// Public collector status metrics
+	publicUpDesc               = prometheus.NewDesc(publicPrefix+"up", "Zero if there was an error during the last refresh try.", nil, nil)
+	publicRefreshIntervalDesc  = prometheus.NewDesc(publicPrefix+"refresh_interval_seconds", "Contains the configured refresh interval in seconds. This is provided as a convenience for calculations with the cache update time.", nil, nil)
+	publicRefreshTimestampDesc = prometheus.NewDesc(publicPrefix+"last_refresh_time", "Contains the time of the last refresh try, successful or not.", nil, nil)
+	publicRefreshDurationDesc  = prometheus.NewDesc(publicPrefix+"last_refresh_duration_seconds", "Contains the time it took for the last refresh to complete, even if it was unsuccessful.", nil, nil)
+	publicCacheTimestampDesc   = prometheus.NewDesc(publicPrefix+"cache_updated_time", "Contains the time of the cached data.", nil, nil)
+
+	publicStationCountDesc = prometheus.NewDesc(publicPrefix+"station_count", "Number of public stations in the area reporting the measurement", publicCountLabelNames, nil)
+
+	publicMeasurements = []publicMeasurement{
+		{
+			name:        "temperature",
+			value:       func(s PublicStation) *float64 { return s.Temperature },
+			areaDesc:    prometheus.NewDesc(publicPrefix+"temperature_celsius", "Temperature of the public stations in the area in celsius", publicAggregationLabelNames, nil),
+			stationDesc: prometheus.NewDesc(publicPrefix+"station_temperature_celsius", "Temperature measurement of a public station in celsius", v2LabelNames, nil),
+		},
+		{
+			name:        "humidity",
+			value:       func(s PublicStation) *float64 { return s.Humidity },
+			areaDesc:    prometheus.NewDesc(publicPrefix+"humidity_percent", "Relative humidity of the public stations in the area in percent", publicAggregationLabelNames, nil),
+			stationDesc: prometheus.NewDesc(publicPrefix+"station_humidity_percent", "Relative humidity measurement of a public station in percent", v2LabelNames, nil),
+		},
+		{
+			name:        "pressure",
+			value:       func(s PublicStation) *float64 { return s.Pressure },
+			areaDesc:    prometheus.NewDesc(publicPrefix+"pressure_mb", "Atmospheric pressure of the public stations in the area in millibar", publicAggregationLabelNames, nil),
+			stationDesc: prometheus.NewDesc(publicPrefix+"station_pressure_mb", "Atmospheric pressure measurement of a public station in millibar", v2LabelNames, nil),
+		},
+		{
+			name:        "rain",
+			value:       func(s PublicStation) *float64 { return s.Rain },
+			areaDesc:    prometheus.NewDesc(publicPrefix+"rain_1h_mm", "Rain amount of the last hour of the public stations in the area in millimeters", publicAggregationLabelNames, nil),
+			stationDesc: prometheus.NewDesc(publicPrefix+"station_rain_1h_mm", "Rain amount of the last hour of a public station in millimeters", v2LabelNames, nil),
+		},
+		{
+			name:        "wind_strength",
+			value:       func(s PublicStation) *float64 { return s.WindStrength },
+			areaDesc:    prometheus.NewDesc(publicPrefix+"wind_strength_kph", "Wind strength of the public stations in the area in kilometers per hour", publicAggregationLabelNames, nil),
+			stationDesc: prometheus.NewDesc(publicPrefix+"station_wind_strength_kph", "Wind strength measurement of a public station in kilometers per hour", v2LabelNames, nil),
+		},
+	}
+)
+
+// PublicCollector is a Prometheus collector for the public weather stations of an area.
+// It exposes the median, minimum and maximum of every measurement and optionally a series per station.
+type PublicCollector struct {
+	log           logrus.FieldLogger
+	store         *Store
+	stationSeries bool
+}
+
+// NewPublicCollector creates a PublicCollector which reads the public stations from the store.
+// If stationSeries is set, the measurements of every station are exported as well.
+func NewPublicCollector(log logrus.FieldLogger, store *Store, stationSeries bool) *PublicCollector {
+	return &PublicCollector{
+		log:           log,
+		store:         store,
+		stationSeries: stationSeries,
+	}
+}
+
+func (c *PublicCollector) Describe(ch chan<- *prometheus.Desc) {
+	// Status metrics
+	ch <- publicUpDesc
+	ch <- publicRefreshIntervalDesc
+	ch <- publicRefreshTimestampDesc
+	ch <- publicRefreshDurationDesc
+	ch <- publicCacheTimestampDesc
+
+	// Data metrics
+	ch <- publicStationCountDesc
+	for _, m := range publicMeasurements {
+		ch <- m.areaDesc
+		if c.stationSeries {
+			ch <- m.stationDesc
+		}
+	}
+}
+
+func (c *PublicCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.store.Public()
+
+	upValue := 1.0
+	if !snapshot.Up() {
+		upValue = 0
+	}
+
+	sendMetric(c.log, ch, publicUpDesc, prometheus.GaugeValue, upValue)
+	sendMetric(c.log, ch, publicRefreshIntervalDesc, prometheus.GaugeValue, c.store.PublicRefreshInterval().Seconds())
+	sendMetric(c.log, ch, publicRefreshTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.LastRefresh))
+	sendMetric(c.log, ch, publicRefreshDurationDesc, prometheus.GaugeValue, snapshot.LastRefreshDuration.Seconds())
+	sendMetric(c.log, ch, publicCacheTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.CacheTimestamp))
+
+	if snapshot.Data == nil {
+		return
+	}
+
+	for _, m := range publicMeasurements {
+		var values []float64
+		for _, station := range snapshot.Data.Stations {
+			value := m.value(station)
+			if value == nil {
+				continue
+			}
+
+			values = append(values, *value)
+			if c.stationSeries {
+				labels := []string{DeviceClassPublic, station.ID, "", "", station.City}
+				sendMetric(c.log, ch, m.stationDesc, prometheus.GaugeValue, *value, labels...)
+			}
+		}
+
+		sendMetric(c.log, ch, publicStationCountDesc, prometheus.GaugeValue, float64(len(values)), m.name)
+		if len(values) == 0 {
+			continue
+		}
+
+		sort.Float64s(values)
+		sendMetric(c.log, ch, m.areaDesc, prometheus.GaugeValue, median(values), "median")
+		sendMetric(c.log, ch, m.areaDesc, prometheus.GaugeValue, values[0], "min")
+		sendMetric(c.log, ch, m.areaDesc, prometheus.GaugeValue, values[len(values)-1], "max")
+	}
+}
+
+// median returns the median of the sorted values.
+func median(sorted []float64) float64 {
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
+
+// publicDataStation is a single station as returned by getpublicdata.
+type publicDataStation struct {
+	ID    string `json:"_id"`
+	Place struct {
+		Location []float64 `json:"location"`
+		Altitude float64   `json:"altitude"`
+		City     string    `json:"city"`
+	} `json:"place"`
+	Measures map[string]struct {
+		// Res contains the values by time for modules measuring the types listed in Type.
+		Res  map[string][]*float64 `json:"res"`
+		Type []string              `json:"type"`
+
+		Rain60Min    *float64 `json:"rain_60min"`
+		WindStrength *float64 `json:"wind_strength"`
+	} `json:"measures"`
+}
+
+func (s publicDataStation) toStation() PublicStation {
+	station := PublicStation{
+		ID:       s.ID,
+		City:     s.Place.City,
+		Altitude: s.Place.Altitude,
+	}
+
+	if len(s.Place.Location) == 2 {
+		station.Longitude = s.Place.Location[0]
+		station.Latitude = s.Place.Location[1]
+	}
+
+	for _, module := range s.Measures {
+		if module.Rain60Min != nil {
+			station.Rain = module.Rain60Min
+		}
+		if module.WindStrength != nil {
+			station.WindStrength = module.WindStrength
+		}
+
+		// Only the latest values are used.
+		latest := int64(math.MinInt64)
+		var values []*float64
+		for key, v := range module.Res {
+			ts, err := strconv.ParseInt(key, 10, 64)
+			if err != nil || ts < latest {
+				continue
+			}
+			latest = ts
+			values = v
+		}
+
+		for i, t := range module.Type {
+			if i >= len(values) || values[i] == nil {
+				continue
+			}
+
+			switch t {
+			case "temperature":
+				station.Temperature = values[i]
+			case "humidity":
+				station.Humidity = values[i]
+			case "pressure":
+				station.Pressure = values[i]
+			}
+		}
+	}
+
+	return station
+}
+
+// FetchPublicData reads the public weather stations in the area.
+func FetchPublicData(client *http.Client, area BoundingBox) (*PublicResponse, error) {
+	query := url.Values{}
+	query.Set("lat_ne", strconv.FormatFloat(area.LatNE, 'f', -1, 64))
+	query.Set("lon_ne", strconv.FormatFloat(area.LonNE, 'f', -1, 64))
+	query.Set("lat_sw", strconv.FormatFloat(area.LatSW, 'f', -1, 64))
+	query.Set("lon_sw", strconv.FormatFloat(area.LonSW, 'f', -1, 64))
+	query.Set("filter", "true")
+
+	var data struct {
+		Body []publicDataStation `json:"body"`
+	}
+	if err := getJSON(client, publicDataURL+"?"+query.Encode(), &data); err != nil {
+		return nil, err
+	}
+
+	result := &PublicResponse{
+		Stations: make([]PublicStation, 0, len(data.Body)),
+	}
+	for _, s := range data.Body {
+		result.Stations = append(result.Stations, s.toStation())
+	}
+
+	return result, nil
+}
+
+// NewPublicReadFunction creates a reader function for the public weather stations in the area.
+func NewPublicReadFunction(getCurrentToken func() (*oauth2.Token, error), apiClient *http.Client, area BoundingBox) PublicReadFunction {
+	return func() (*PublicResponse, error) {
+		httpClient, err := api.AuthenticatedClient(getCurrentToken, apiClient)
+		if err != nil {
+			return nil, err
+		}
+		return FetchPublicData(httpClient, area)
+	}
+}
@@ -0,0 +1,184 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+const testPublicJSON = `[
+	{
+		"_id": "70:ee:50:00:00:01",
+		"place": {"location": [11.5, 48.1], "altitude": 520, "city": "Munich"},
+		"measures": {
+			"02:00:00:00:00:01": {
+				"res": {"1700000000": [10.5, 80], "1700000600": [11.0, 78]},
+				"type": ["temperature", "humidity"]
+			},
+			"70:ee:50:00:00:01": {
+				"res": {"1700000600": [1013.2]},
+				"type": ["pressure"]
+			},
+			"05:00:00:00:00:01": {"rain_60min": 0.5, "rain_24h": 3.1, "rain_live": 0, "rain_timeutc": 1700000600},
+			"06:00:00:00:00:01": {"wind_strength": 12, "wind_angle": 180, "gust_strength": 20, "gust_angle": 190, "wind_timeutc": 1700000600}
+		}
+	},
+	{
+		"_id": "70:ee:50:00:00:02",
+		"place": {"location": [11.6, 48.2], "altitude": 510, "city": "Munich"},
+		"measures": {
+			"02:00:00:00:00:02": {
+				"res": {"1700000600": [13.0, 70]},
+				"type": ["temperature", "humidity"]
+			}
+		}
+	},
+	{
+		"_id": "70:ee:50:00:00:03",
+		"place": {"location": [11.7, 48.15], "altitude": 530, "city": "Munich"},
+		"measures": {
+			"02:00:00:00:00:03": {
+				"res": {"1700000600": [12.0, 74]},
+				"type": ["temperature", "humidity"]
+			}
+		}
+	}
+]`
+
+func newTestPublicStore(t *testing.T, log logrus.FieldLogger) *Store {
+	t.Helper()
+
+	var stations []publicDataStation
+	if err := json.Unmarshal([]byte(testPublicJSON), &stations); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	public := &PublicResponse{}
+	for _, s := range stations {
+		public.Stations = append(public.Stations, s.toStation())
+	}
+
+	store := NewStore(log, nil, nil, nil, time.Hour)
+	store.EnablePublic(func() (*PublicResponse, error) {
+		return public, nil
+	}, 30*time.Minute)
+	store.RefreshPublic()
+
+	return store
+}
+
+func TestPublicStation(t *testing.T) {
+	log := logrus.New()
+	store := newTestPublicStore(t, log)
+
+	temperature := 11.0
+	humidity := 78.0
+	pressure := 1013.2
+	rain := 0.5
+	wind := 12.0
+	want := PublicStation{
+		ID:           "70:ee:50:00:00:01",
+		City:         "Munich",
+		Latitude:     48.1,
+		Longitude:    11.5,
+		Altitude:     520,
+		Temperature:  &temperature,
+		Humidity:     &humidity,
+		Pressure:     &pressure,
+		Rain:         &rain,
+		WindStrength: &wind,
+	}
+
+	got := store.Public().Data.Stations[0]
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("station differs: -got+want\n%s", diff)
+	}
+}
+
+func TestPublicCollector(t *testing.T) {
+	log := logrus.New()
+	store := newTestPublicStore(t, log)
+
+	want := `# HELP netatmo_public_humidity_percent Relative humidity of the public stations in the area in percent
+# TYPE netatmo_public_humidity_percent gauge
+netatmo_public_humidity_percent{aggregation="max"} 78
+netatmo_public_humidity_percent{aggregation="median"} 74
+netatmo_public_humidity_percent{aggregation="min"} 70
+# HELP netatmo_public_pressure_mb Atmospheric pressure of the public stations in the area in millibar
+# TYPE netatmo_public_pressure_mb gauge
+netatmo_public_pressure_mb{aggregation="max"} 1013.2
+netatmo_public_pressure_mb{aggregation="median"} 1013.2
+netatmo_public_pressure_mb{aggregation="min"} 1013.2
+# HELP netatmo_public_station_count Number of public stations in the area reporting the measurement
+# TYPE netatmo_public_station_count gauge
+netatmo_public_station_count{measurement="humidity"} 3
+netatmo_public_station_count{measurement="pressure"} 1
+netatmo_public_station_count{measurement="rain"} 1
+netatmo_public_station_count{measurement="temperature"} 3
+netatmo_public_station_count{measurement="wind_strength"} 1
+# HELP netatmo_public_station_temperature_celsius Temperature measurement of a public station in celsius
+# TYPE netatmo_public_station_temperature_celsius gauge
+netatmo_public_station_temperature_celsius{device_class="public",device_id="70:ee:50:00:00:01",home="",module="",station="Munich"} 11
+netatmo_public_station_temperature_celsius{device_class="public",device_id="70:ee:50:00:00:02",home="",module="",station="Munich"} 13
+netatmo_public_station_temperature_celsius{device_class="public",device_id="70:ee:50:00:00:03",home="",module="",station="Munich"} 12
+# HELP netatmo_public_temperature_celsius Temperature of the public stations in the area in celsius
+# TYPE netatmo_public_temperature_celsius gauge
+netatmo_public_temperature_celsius{aggregation="max"} 13
+netatmo_public_temperature_celsius{aggregation="median"} 12
+netatmo_public_temperature_celsius{aggregation="min"} 11
+`
+
+	metricNames := []string{
+		"netatmo_public_humidity_percent",
+		"netatmo_public_pressure_mb",
+		"netatmo_public_station_count",
+		"netatmo_public_station_temperature_celsius",
+		"netatmo_public_temperature_celsius",
+	}
+
+	collector := NewPublicCollector(log, store, true)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tt := []struct {
+		desc   string
+		values []float64
+		want   float64
+	}{
+		{
+			desc:   "single",
+			values: []float64{5},
+			want:   5,
+		},
+		{
+			desc:   "odd",
+			values: []float64{1, 2, 10},
+			want:   2,
+		},
+		{
+			desc:   "even",
+			values: []float64{1, 2, 4, 10},
+			want:   3,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			if got := median(tc.values); got != tc.want {
+				t.Errorf("got median %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
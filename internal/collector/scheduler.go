@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// jitterFactor is the maximum deviation from the refresh interval as a fraction of the interval.
+const jitterFactor = 0.1
+
+type job struct {
+	name     string
+	interval time.Duration
+	refresh  func()
+}
+
+// Scheduler refreshes the cached data in the background, independent of Prometheus scrapes.
+// Every job is run once on startup and then repeatedly after its interval, with some jitter added.
+type Scheduler struct {
+	log  logrus.FieldLogger
+	jobs []job
+}
+
+// NewScheduler creates a new Scheduler without any jobs.
+func NewScheduler(log logrus.FieldLogger) *Scheduler {
+	return &Scheduler{
+		log: log,
+	}
+}
+
+// Add registers a refresh function which is called in the given interval. It has to be called before Run.
+func (s *Scheduler) Add(name string, interval time.Duration, refresh func()) {
+	s.jobs = append(s.jobs, job{
+		name:     name,
+		interval: interval,
+		refresh:  refresh,
+	})
+}
+
+// Run starts all jobs and blocks until the context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, j := range s.jobs {
+		wg.Add(1)
+		go func(j job) {
+			defer wg.Done()
+			s.runJob(ctx, j)
+		}(j)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) runJob(ctx context.Context, j job) {
+	for {
+		j.refresh()
+
+		delay := withJitter(j.interval)
+		s.log.Debugf("Next %s refresh in %s.", j.name, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func withJitter(interval time.Duration) time.Duration {
+	jitter := time.Duration((rand.Float64()*2 - 1) * jitterFactor * float64(interval))
+	return interval + jitter
+}
@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSchedulerRefreshesOnStartup(t *testing.T) {
+	refreshed := make(chan struct{}, 1)
+
+	scheduler := NewScheduler(logrus.New())
+	scheduler.Add("test", time.Hour, func() {
+		refreshed <- struct{}{}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("no refresh on startup")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after cancel")
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	interval := 10 * time.Minute
+	for i := 0; i < 100; i++ {
+		got := withJitter(interval)
+		if got < 9*time.Minute || got > 11*time.Minute {
+			t.Fatalf("got delay %s, want between 9m and 11m", got)
+		}
+	}
+}
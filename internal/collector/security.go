@@ -0,0 +1,162 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// DeviceClassSecurity is the device class of Netatmo Security cameras, doorbells and tags.
+const DeviceClassSecurity = "security"
+
+var (
+	securityPrefix = prefix + "security_"
+
+	// securityModuleTypes contains the module types which belong to Netatmo Security.
+	securityModuleTypes = map[string]bool{
+		"NACamera":     true, // Indoor camera (Welcome)
+		"NOC":          true, // Outdoor camera (Presence)
+		"NDB":          true, // Video doorbell
+		"NACamDoorTag": true, // Door and window tag
+		"NIS":          true, // Indoor siren
+	}
+
+	// lightModes contains the known values of light_mode_status of outdoor cameras.
+	lightModes = []string{"auto", "on", "off"}
+
+	// securityBatteryStates contains the known values of battery_state.
+	securityBatteryStates = []string{"full", "high", "medium", "low", "very_low"}
+
+	securityLightModeLabelNames = append(LabelNames(), "mode")
+	securityBatteryLabelNames   = append(LabelNames(), "state")
+
+	// Security collector status metrics
+	securityUpDesc               = prometheus.NewDesc(securityPrefix+"up", "Zero if there was an error during the last refresh try.", nil, nil)
+	securityRefreshIntervalDesc  = prometheus.NewDesc(securityPrefix+"refresh_interval_seconds", "Contains the configured refresh interval in seconds. This is provided as a convenience for calculations with the cache update time.", nil, nil)
+	securityRefreshTimestampDesc = prometheus.NewDesc(securityPrefix+"last_refresh_time", "Contains the time of the last refresh try, successful or not.", nil, nil)
+	securityRefreshDurationDesc  = prometheus.NewDesc(securityPrefix+"last_refresh_duration_seconds", "Contains the time it took for the last refresh to complete, even if it was unsuccessful.", nil, nil)
+	securityCacheTimestampDesc   = prometheus.NewDesc(securityPrefix+"cache_updated_time", "Contains the time of the cached data.", nil, nil)
+
+	// Camera metrics
+	securityMonitoringDesc = prometheus.NewDesc(securityPrefix+"monitoring", "One if monitoring is enabled on the camera", v2LabelNames, nil)
+	securitySDStatusDesc   = prometheus.NewDesc(securityPrefix+"sd_status", "Status of the SD card (1: missing, 2: inserted, 3: formatted, 4: ready, 5: defect, 6: incompatible, 7: too small)", v2LabelNames, nil)
+	securityAlimStatusDesc = prometheus.NewDesc(securityPrefix+"alim_status", "Status of the power supply (1: incorrect power adapter, 2: correct power adapter)", v2LabelNames, nil)
+	securityLightModeDesc  = prometheus.NewDesc(securityPrefix+"light_mode", "Current mode of the flood light of outdoor cameras", securityLightModeLabelNames, nil)
+	securityLastEventDesc  = prometheus.NewDesc(securityPrefix+"last_event_time", "Time of the latest event of the device", v2LabelNames, nil)
+
+	// Tag metrics
+	securityDoorOpenDesc     = prometheus.NewDesc(securityPrefix+"door_open", "One if the door or window of the tag is open", v2LabelNames, nil)
+	securityBatteryStateDesc = prometheus.NewDesc(securityPrefix+"battery_state", "Battery state as reported by Netatmo", securityBatteryLabelNames, nil)
+	securityRFDesc           = prometheus.NewDesc(securityPrefix+"rf_signal_strength", "RF signal strength (90: lowest, 60: highest)", v2LabelNames, nil)
+	securityWifiDesc         = prometheus.NewDesc(securityPrefix+"wifi_signal_strength", "Wifi signal strength (86: bad, 71: avg, 56: good)", v2LabelNames, nil)
+)
+
+// SecurityCollector is a Prometheus collector for Netatmo Security cameras, doorbells and door/window tags.
+// It uses the unified V2 labels with device_class "security".
+type SecurityCollector struct {
+	log   logrus.FieldLogger
+	store *Store
+}
+
+// NewSecurityCollector creates a SecurityCollector which reads the homes from the store.
+func NewSecurityCollector(log logrus.FieldLogger, store *Store) *SecurityCollector {
+	return &SecurityCollector{
+		log:   log,
+		store: store,
+	}
+}
+
+func (c *SecurityCollector) Describe(ch chan<- *prometheus.Desc) {
+	// Status metrics
+	ch <- securityUpDesc
+	ch <- securityRefreshIntervalDesc
+	ch <- securityRefreshTimestampDesc
+	ch <- securityRefreshDurationDesc
+	ch <- securityCacheTimestampDesc
+
+	// Data metrics
+	ch <- securityMonitoringDesc
+	ch <- securitySDStatusDesc
+	ch <- securityAlimStatusDesc
+	ch <- securityLightModeDesc
+	ch <- securityLastEventDesc
+	ch <- securityDoorOpenDesc
+	ch <- securityBatteryStateDesc
+	ch <- securityRFDesc
+	ch <- securityWifiDesc
+}
+
+func (c *SecurityCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.store.Homes()
+
+	upValue := 1.0
+	if !snapshot.Up() {
+		upValue = 0
+	}
+
+	sendMetric(c.log, ch, securityUpDesc, prometheus.GaugeValue, upValue)
+	sendMetric(c.log, ch, securityRefreshIntervalDesc, prometheus.GaugeValue, c.store.RefreshInterval().Seconds())
+	sendMetric(c.log, ch, securityRefreshTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.LastRefresh))
+	sendMetric(c.log, ch, securityRefreshDurationDesc, prometheus.GaugeValue, snapshot.LastRefreshDuration.Seconds())
+	sendMetric(c.log, ch, securityCacheTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.CacheTimestamp))
+
+	if snapshot.Data == nil {
+		return
+	}
+
+	for _, home := range snapshot.Data.Homes {
+		c.collectHome(ch, home)
+	}
+}
+
+func (c *SecurityCollector) collectHome(ch chan<- prometheus.Metric, home Home) {
+	moduleStatus := home.moduleStatus()
+	moduleNames := home.moduleNames()
+	lastEvents := home.lastEvents()
+
+	for _, module := range home.Modules {
+		if !securityModuleTypes[module.Type] {
+			continue
+		}
+
+		status, ok := moduleStatus[module.ID]
+		if !ok {
+			continue
+		}
+
+		labels := home.moduleLabels(DeviceClassSecurity, module, moduleNames)
+
+		if status.Monitoring != "" {
+			sendMetric(c.log, ch, securityMonitoringDesc, prometheus.GaugeValue, boolValue(status.Monitoring == "on"), labels...)
+		}
+		if status.SDStatus != nil {
+			sendMetric(c.log, ch, securitySDStatusDesc, prometheus.GaugeValue, float64(*status.SDStatus), labels...)
+		}
+		if status.AlimStatus != nil {
+			sendMetric(c.log, ch, securityAlimStatusDesc, prometheus.GaugeValue, float64(*status.AlimStatus), labels...)
+		}
+		if status.LightModeStatus != "" {
+			sendStateSet(c.log, ch, securityLightModeDesc, lightModes, status.LightModeStatus, labels...)
+		}
+		if lastEvent, ok := lastEvents[module.ID]; ok {
+			sendMetric(c.log, ch, securityLastEventDesc, prometheus.GaugeValue, float64(lastEvent), labels...)
+		}
+		if module.Type == "NACamDoorTag" {
+			// Tags also report "no_news" or "undefined", which are neither open nor closed.
+			switch status.Status {
+			case "open":
+				sendMetric(c.log, ch, securityDoorOpenDesc, prometheus.GaugeValue, 1, labels...)
+			case "closed":
+				sendMetric(c.log, ch, securityDoorOpenDesc, prometheus.GaugeValue, 0, labels...)
+			}
+		}
+		if status.BatteryState != "" {
+			sendStateSet(c.log, ch, securityBatteryStateDesc, securityBatteryStates, status.BatteryState, labels...)
+		}
+		if status.RFStrength != nil {
+			sendMetric(c.log, ch, securityRFDesc, prometheus.GaugeValue, float64(*status.RFStrength), labels...)
+		}
+		if status.WifiStrength != nil {
+			sendMetric(c.log, ch, securityWifiDesc, prometheus.GaugeValue, float64(*status.WifiStrength), labels...)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSecurityCollector(t *testing.T) {
+	log := logrus.New()
+	store := newTestHomesStore(t, log)
+
+	want := `# HELP netatmo_security_alim_status Status of the power supply (1: incorrect power adapter, 2: correct power adapter)
+# TYPE netatmo_security_alim_status gauge
+netatmo_security_alim_status{device_class="security",device_id="70:ee:50:00:00:02",home="Home",module="Camera",station="Camera"} 2
+netatmo_security_alim_status{device_class="security",device_id="70:ee:50:00:00:03",home="Home",module="Garden",station="Garden"} 2
+# HELP netatmo_security_battery_state Battery state as reported by Netatmo
+# TYPE netatmo_security_battery_state gauge
+netatmo_security_battery_state{device_class="security",device_id="70:00:00:00:00:04",home="Home",module="Front door",state="full",station="Camera"} 0
+netatmo_security_battery_state{device_class="security",device_id="70:00:00:00:00:04",home="Home",module="Front door",state="high",station="Camera"} 0
+netatmo_security_battery_state{device_class="security",device_id="70:00:00:00:00:04",home="Home",module="Front door",state="low",station="Camera"} 1
+netatmo_security_battery_state{device_class="security",device_id="70:00:00:00:00:04",home="Home",module="Front door",state="medium",station="Camera"} 0
+netatmo_security_battery_state{device_class="security",device_id="70:00:00:00:00:04",home="Home",module="Front door",state="very_low",station="Camera"} 0
+# HELP netatmo_security_door_open One if the door or window of the tag is open
+# TYPE netatmo_security_door_open gauge
+netatmo_security_door_open{device_class="security",device_id="70:00:00:00:00:04",home="Home",module="Front door",station="Camera"} 1
+# HELP netatmo_security_last_event_time Time of the latest event of the device
+# TYPE netatmo_security_last_event_time gauge
+netatmo_security_last_event_time{device_class="security",device_id="70:ee:50:00:00:02",home="Home",module="Camera",station="Camera"} 1.7000001e+09
+# HELP netatmo_security_light_mode Current mode of the flood light of outdoor cameras
+# TYPE netatmo_security_light_mode gauge
+netatmo_security_light_mode{device_class="security",device_id="70:ee:50:00:00:03",home="Home",mode="auto",module="Garden",station="Garden"} 1
+netatmo_security_light_mode{device_class="security",device_id="70:ee:50:00:00:03",home="Home",mode="off",module="Garden",station="Garden"} 0
+netatmo_security_light_mode{device_class="security",device_id="70:ee:50:00:00:03",home="Home",mode="on",module="Garden",station="Garden"} 0
+# HELP netatmo_security_monitoring One if monitoring is enabled on the camera
+# TYPE netatmo_security_monitoring gauge
+netatmo_security_monitoring{device_class="security",device_id="70:ee:50:00:00:02",home="Home",module="Camera",station="Camera"} 1
+netatmo_security_monitoring{device_class="security",device_id="70:ee:50:00:00:03",home="Home",module="Garden",station="Garden"} 0
+# HELP netatmo_security_rf_signal_strength RF signal strength (90: lowest, 60: highest)
+# TYPE netatmo_security_rf_signal_strength gauge
+netatmo_security_rf_signal_strength{device_class="security",device_id="70:00:00:00:00:04",home="Home",module="Front door",station="Camera"} 80
+# HELP netatmo_security_sd_status Status of the SD card (1: missing, 2: inserted, 3: formatted, 4: ready, 5: defect, 6: incompatible, 7: too small)
+# TYPE netatmo_security_sd_status gauge
+netatmo_security_sd_status{device_class="security",device_id="70:ee:50:00:00:02",home="Home",module="Camera",station="Camera"} 4
+netatmo_security_sd_status{device_class="security",device_id="70:ee:50:00:00:03",home="Home",module="Garden",station="Garden"} 1
+# HELP netatmo_security_wifi_signal_strength Wifi signal strength (86: bad, 71: avg, 56: good)
+# TYPE netatmo_security_wifi_signal_strength gauge
+netatmo_security_wifi_signal_strength{device_class="security",device_id="70:ee:50:00:00:02",home="Home",module="Camera",station="Camera"} 60
+`
+
+	metricNames := []string{
+		"netatmo_security_alim_status",
+		"netatmo_security_battery_state",
+		"netatmo_security_door_open",
+		"netatmo_security_last_event_time",
+		"netatmo_security_light_mode",
+		"netatmo_security_monitoring",
+		"netatmo_security_rf_signal_strength",
+		"netatmo_security_sd_status",
+		"netatmo_security_wifi_signal_strength",
+	}
+
+	collector := NewSecurityCollector(log, store)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), metricNames...); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,286 @@
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Snapshot contains the data last read from a single Netatmo API endpoint together with the state of the refresh.
+type Snapshot[T any] struct {
+	// Data contains the result of the last successful refresh. It is nil if no refresh has been successful yet.
+	Data *T
+	// CacheTimestamp is the time of the last successful refresh.
+	CacheTimestamp time.Time
+	// LastRefresh is the time of the last refresh try, successful or not.
+	LastRefresh         time.Time
+	LastRefreshError    error
+	LastRefreshDuration time.Duration
+}
+
+// Up returns true if the last refresh try was successful.
+func (s Snapshot[T]) Up() bool {
+	return !s.LastRefresh.IsZero() && s.LastRefreshError == nil
+}
+
+// endpoint caches the data of a single Netatmo API endpoint.
+type endpoint[T any] struct {
+	name string
+	read func() (*T, error)
+
+	lock     sync.RWMutex
+	inflight chan struct{}
+	snapshot Snapshot[T]
+}
+
+func newEndpoint[T any](name string, read func() (*T, error)) *endpoint[T] {
+	if read == nil {
+		return nil
+	}
+
+	return &endpoint[T]{
+		name: name,
+		read: read,
+	}
+}
+
+func (e *endpoint[T]) get() Snapshot[T] {
+	if e == nil {
+		return Snapshot[T]{}
+	}
+
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.snapshot
+}
+
+// refresh reads the endpoint and updates the snapshot. If a refresh is already running,
+// refresh waits for it to complete instead of starting another one.
+// If this call refreshed the data successfully, it returns true together with the times of the previous and the current refresh.
+func (e *endpoint[T]) refresh(log logrus.FieldLogger, clock func() time.Time) (previous, current time.Time, ok bool) {
+	e.lock.Lock()
+	if e.inflight != nil {
+		inflight := e.inflight
+		e.lock.Unlock()
+
+		<-inflight
+		return time.Time{}, time.Time{}, false
+	}
+
+	done := make(chan struct{})
+	e.inflight = done
+	e.lock.Unlock()
+
+	log.Debugf("Refreshing %s data.", e.name)
+	start := clock()
+	data, err := e.read()
+	duration := clock().Sub(start)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.inflight = nil
+	close(done)
+
+	e.snapshot.LastRefresh = start
+	e.snapshot.LastRefreshError = err
+	e.snapshot.LastRefreshDuration = duration
+	if err != nil {
+		log.Errorf("Error during %s refresh: %s", e.name, err)
+		return time.Time{}, time.Time{}, false
+	}
+
+	previous = e.snapshot.CacheTimestamp
+	e.snapshot.CacheTimestamp = start
+	e.snapshot.Data = data
+	return previous, start, true
+}
+
+// RefreshHook is called after a successful refresh of an endpoint. previous is the time of the successful
+// refresh before, which is zero for the first refresh after startup.
+type RefreshHook func(name string, previous, current time.Time)
+
+// Store holds the data read from the Netatmo API. It is shared by all collectors and handlers,
+// so that every API endpoint is only read once per refresh interval.
+type Store struct {
+	log             logrus.FieldLogger
+	refreshInterval time.Duration
+	clock           func() time.Time
+
+	weather   *endpoint[WeatherResponse]
+	homecoach *endpoint[HomecoachResponse]
+	homes     *endpoint[HomesResponse]
+	public    *endpoint[PublicResponse]
+
+	// publicInterval is the refresh interval of the public weather stations, which change less often.
+	publicInterval time.Duration
+
+	// homecoachHomes contains the configured home names of HomeCoach devices by ID.
+	homecoachHomes map[string]string
+
+	hooks []RefreshHook
+}
+
+// NewStore creates a new Store. A nil reader function disables the respective endpoint.
+func NewStore(log logrus.FieldLogger, weatherReader WeatherReadFunction, homecoachReader HomecoachReadFunction, homesReader HomesReadFunction, refreshInterval time.Duration) *Store {
+	return &Store{
+		log:             log,
+		refreshInterval: refreshInterval,
+		clock:           time.Now,
+		weather:         newEndpoint(DeviceClassWeather, weatherReader),
+		homecoach:       newEndpoint(DeviceClassHomecoach, homecoachReader),
+		homes:           newEndpoint(endpointHomes, homesReader),
+	}
+}
+
+// EnablePublic enables reading the public weather stations using its own refresh interval.
+// It has to be called before the store is scheduled.
+func (s *Store) EnablePublic(reader PublicReadFunction, interval time.Duration) {
+	s.public = newEndpoint(endpointPublic, reader)
+	s.publicInterval = interval
+}
+
+// SetHomecoachHomes sets the home names of HomeCoach devices by ID. They take precedence over the homes
+// read from homesdata.
+func (s *Store) SetHomecoachHomes(homes map[string]string) {
+	s.homecoachHomes = homes
+}
+
+// RefreshInterval returns the configured refresh interval.
+func (s *Store) RefreshInterval() time.Duration {
+	return s.refreshInterval
+}
+
+// PublicRefreshInterval returns the refresh interval of the public weather stations.
+func (s *Store) PublicRefreshInterval() time.Duration {
+	return s.publicInterval
+}
+
+// WeatherEnabled returns true if the store reads weather station data.
+func (s *Store) WeatherEnabled() bool {
+	return s.weather != nil
+}
+
+// HomecoachEnabled returns true if the store reads HomeCoach data.
+func (s *Store) HomecoachEnabled() bool {
+	return s.homecoach != nil
+}
+
+// HomesEnabled returns true if the store reads the homes used by the Energy and other home-based collectors.
+func (s *Store) HomesEnabled() bool {
+	return s.homes != nil
+}
+
+// PublicEnabled returns true if the store reads public weather stations.
+func (s *Store) PublicEnabled() bool {
+	return s.public != nil
+}
+
+// Weather returns the current weather station snapshot.
+func (s *Store) Weather() Snapshot[WeatherResponse] {
+	return s.weather.get()
+}
+
+// Homecoach returns the current HomeCoach snapshot.
+func (s *Store) Homecoach() Snapshot[HomecoachResponse] {
+	return s.homecoach.get()
+}
+
+// Homes returns the current homes snapshot.
+func (s *Store) Homes() Snapshot[HomesResponse] {
+	return s.homes.get()
+}
+
+// Public returns the current public weather stations snapshot.
+func (s *Store) Public() Snapshot[PublicResponse] {
+	return s.public.get()
+}
+
+// WeatherData returns the cached weather station data and the error of the last refresh.
+func (s *Store) WeatherData() (*WeatherResponse, error) {
+	snapshot := s.Weather()
+	return snapshot.Data, snapshot.LastRefreshError
+}
+
+// HomecoachData returns the cached HomeCoach data and the error of the last refresh.
+func (s *Store) HomecoachData() (*HomecoachResponse, error) {
+	snapshot := s.Homecoach()
+	return snapshot.Data, snapshot.LastRefreshError
+}
+
+// RefreshWeather reads the weather station data from the Netatmo API and updates the cache.
+func (s *Store) RefreshWeather() {
+	if s.weather == nil {
+		return
+	}
+
+	if previous, current, ok := s.weather.refresh(s.log, s.clock); ok {
+		s.runHooks(s.weather.name, previous, current)
+	}
+}
+
+// RefreshHomecoach reads the HomeCoach data from the Netatmo API and updates the cache.
+func (s *Store) RefreshHomecoach() {
+	if s.homecoach == nil {
+		return
+	}
+
+	if previous, current, ok := s.homecoach.refresh(s.log, s.clock); ok {
+		s.runHooks(s.homecoach.name, previous, current)
+	}
+}
+
+// RefreshHomes reads the homes from the Netatmo API and updates the cache.
+func (s *Store) RefreshHomes() {
+	if s.homes == nil {
+		return
+	}
+
+	if previous, current, ok := s.homes.refresh(s.log, s.clock); ok {
+		s.runHooks(s.homes.name, previous, current)
+	}
+}
+
+// RefreshPublic reads the public weather stations from the Netatmo API and updates the cache.
+func (s *Store) RefreshPublic() {
+	if s.public == nil {
+		return
+	}
+
+	if previous, current, ok := s.public.refresh(s.log, s.clock); ok {
+		s.runHooks(s.public.name, previous, current)
+	}
+}
+
+// OnRefresh adds a hook which is called after every successful refresh. Hooks have to be added before
+// the store is refreshed for the first time.
+func (s *Store) OnRefresh(hook RefreshHook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+func (s *Store) runHooks(name string, previous, current time.Time) {
+	for _, hook := range s.hooks {
+		hook(name, previous, current)
+	}
+}
+
+// Schedule adds a refresh job for every enabled endpoint to the scheduler.
+func (s *Store) Schedule(scheduler *Scheduler) {
+	if s.weather != nil {
+		scheduler.Add(s.weather.name, s.refreshInterval, s.RefreshWeather)
+	}
+
+	if s.homecoach != nil {
+		scheduler.Add(s.homecoach.name, s.refreshInterval, s.RefreshHomecoach)
+	}
+
+	if s.homes != nil {
+		scheduler.Add(s.homes.name, s.refreshInterval, s.RefreshHomes)
+	}
+
+	if s.public != nil {
+		scheduler.Add(s.public.name, s.publicInterval, s.RefreshPublic)
+	}
+}
@@ -0,0 +1,183 @@
+package collector
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+func TestStoreSharedBetweenCollectors(t *testing.T) {
+	var weatherCalls, homecoachCalls atomic.Int32
+
+	weatherReader := func() (*WeatherResponse, error) {
+		weatherCalls.Add(1)
+		return &WeatherResponse{}, nil
+	}
+	homecoachReader := func() (*HomecoachResponse, error) {
+		homecoachCalls.Add(1)
+		return &HomecoachResponse{}, nil
+	}
+
+	log := logrus.New()
+	store := NewStore(log, weatherReader, homecoachReader, nil, time.Hour)
+	store.RefreshWeather()
+	store.RefreshHomecoach()
+
+	registryV1 := prometheus.NewRegistry()
+	registryV1.MustRegister(NewWeatherCollector(log, store, time.Hour, StaleDrop, UnitsMetric))
+	registryV1.MustRegister(NewHomecoachCollector(log, store, time.Hour, StaleDrop, UnitsMetric))
+
+	registryV2 := prometheus.NewRegistry()
+	registryV2.MustRegister(UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsMetric))
+
+	for i := 0; i < 3; i++ {
+		for _, r := range []*prometheus.Registry{registryV1, registryV2} {
+			if _, err := r.Gather(); err != nil {
+				t.Fatalf("error gathering metrics: %s", err)
+			}
+		}
+	}
+
+	if got := weatherCalls.Load(); got != 1 {
+		t.Errorf("got %d weather reads, want 1", got)
+	}
+
+	if got := homecoachCalls.Load(); got != 1 {
+		t.Errorf("got %d homecoach reads, want 1", got)
+	}
+}
+
+func TestStoreRefreshSingleflight(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	weatherReader := func() (*WeatherResponse, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return &WeatherResponse{}, nil
+	}
+
+	store := NewStore(logrus.New(), weatherReader, nil, nil, time.Hour)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		store.RefreshWeather()
+	}()
+	<-started
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.RefreshWeather()
+		}()
+	}
+
+	// give the other refreshes time to join the running one
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("got %d weather reads, want 1", got)
+	}
+
+	if !store.Weather().Up() {
+		t.Error("expected weather snapshot to be up")
+	}
+}
+
+func TestStoreTargetDeviceIDs(t *testing.T) {
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(testWeatherJSON), &weather); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	homes := &HomesResponse{
+		Homes: []Home{
+			{
+				ID:   "home1",
+				Name: "Home",
+				Modules: []Module{
+					{ID: "gateway", Type: "BNLP", Name: "Gateway"},
+					{ID: "plug", Type: "NLP", Name: "Washing machine", Bridge: "gateway"},
+				},
+			},
+			{
+				ID:   "home2",
+				Name: "Cottage",
+				Modules: []Module{
+					{ID: "thermostat", Type: "NATherm1", Name: "Thermostat"},
+				},
+			},
+		},
+	}
+
+	store := NewStore(logrus.New(), func() (*WeatherResponse, error) {
+		return &weather, nil
+	}, nil, func() (*HomesResponse, error) {
+		return homes, nil
+	}, time.Hour)
+	store.RefreshWeather()
+	store.RefreshHomes()
+
+	tests := []struct {
+		desc     string
+		homeID   string
+		deviceID string
+		want     []string
+	}{
+		{
+			desc:   "home",
+			homeID: "home1",
+			want:   []string{"70:ee:50:00:00:01", "02:00:00:00:00:01", "05:00:00:00:00:01", "06:00:00:00:00:01", "gateway", "plug"},
+		},
+		{
+			desc:   "home without weather station",
+			homeID: "home2",
+			want:   []string{"thermostat"},
+		},
+		{
+			desc:     "station",
+			deviceID: "70:ee:50:00:00:02",
+			want:     []string{"70:ee:50:00:00:02", "02:00:00:00:00:02"},
+		},
+		{
+			desc:     "module",
+			deviceID: "06:00:00:00:00:01",
+			want:     []string{"06:00:00:00:00:01"},
+		},
+		{
+			desc:     "bridge",
+			deviceID: "gateway",
+			want:     []string{"gateway", "plug"},
+		},
+		{
+			desc:   "unknown home",
+			homeID: "home3",
+			want:   nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := store.TargetDeviceIDs(tc.homeID, tc.deviceID)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("device IDs differ: -got+want\n%s", diff)
+			}
+		})
+	}
+}
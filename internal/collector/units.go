@@ -0,0 +1,75 @@
+package collector
+
+// UnitSystem defines the units which are exported in addition to the metric units.
+type UnitSystem string
+
+const (
+	// UnitsMetric only exports metric units.
+	UnitsMetric UnitSystem = "metric"
+	// UnitsImperial exports imperial units in addition to the metric units.
+	UnitsImperial UnitSystem = "imperial"
+	// UnitsAccount exports the imperial units selected in the preferences of the Netatmo account.
+	UnitsAccount UnitSystem = "account"
+)
+
+// Unit preferences of the Netatmo account, which select imperial units.
+const (
+	accountUnitImperial     = 1
+	accountWindUnitMPH      = 1
+	accountPressureUnitInHg = 1
+)
+
+// Administrative contains the preferences of the Netatmo account.
+type Administrative struct {
+	Lang         string `json:"lang"`
+	RegLocale    string `json:"reg_locale"`
+	Country      string `json:"country"`
+	Unit         int    `json:"unit"`
+	Windunit     int    `json:"windunit"`
+	Pressureunit int    `json:"pressureunit"`
+	FeelLikeAlgo int    `json:"feel_like_algo"`
+}
+
+// imperialUnits defines for which measurements imperial units are exported.
+type imperialUnits struct {
+	// temperature and rain use fahrenheit and inches.
+	temperature  bool
+	windStrength bool
+	pressure     bool
+}
+
+// imperial returns the measurements with imperial units, using the account preferences for UnitsAccount.
+func (u UnitSystem) imperial(account Administrative) imperialUnits {
+	switch u {
+	case UnitsImperial:
+		return imperialUnits{
+			temperature:  true,
+			windStrength: true,
+			pressure:     true,
+		}
+	case UnitsAccount:
+		return imperialUnits{
+			temperature:  account.Unit == accountUnitImperial,
+			windStrength: account.Windunit == accountWindUnitMPH,
+			pressure:     account.Pressureunit == accountPressureUnitInHg,
+		}
+	default:
+		return imperialUnits{}
+	}
+}
+
+func celsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+func kphToMPH(kph float64) float64 {
+	return kph / 1.609344
+}
+
+func mbarToInHg(mbar float64) float64 {
+	return mbar / 33.8638866667
+}
+
+func mmToInches(mm float64) float64 {
+	return mm / 25.4
+}
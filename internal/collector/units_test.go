@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestUnitSystemImperial(t *testing.T) {
+	tt := []struct {
+		desc    string
+		units   UnitSystem
+		account Administrative
+		want    imperialUnits
+	}{
+		{
+			desc:    "metric",
+			units:   UnitsMetric,
+			account: Administrative{Unit: 1, Windunit: 1, Pressureunit: 1},
+			want:    imperialUnits{},
+		},
+		{
+			desc:  "imperial",
+			units: UnitsImperial,
+			want: imperialUnits{
+				temperature:  true,
+				windStrength: true,
+				pressure:     true,
+			},
+		},
+		{
+			desc:    "account imperial with metric pressure",
+			units:   UnitsAccount,
+			account: Administrative{Unit: 1, Windunit: 1, Pressureunit: 0},
+			want: imperialUnits{
+				temperature:  true,
+				windStrength: true,
+			},
+		},
+		{
+			desc:    "account with wind in knots",
+			units:   UnitsAccount,
+			account: Administrative{Unit: 0, Windunit: 4, Pressureunit: 1},
+			want: imperialUnits{
+				pressure: true,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.units.imperial(tc.account)
+			if diff := cmp.Diff(got, tc.want, cmp.AllowUnexported(imperialUnits{})); diff != "" {
+				t.Errorf("units differ: -got+want\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnitConversion(t *testing.T) {
+	tt := []struct {
+		desc    string
+		convert func(float64) float64
+		value   float64
+		want    float64
+	}{
+		{desc: "fahrenheit", convert: celsiusToFahrenheit, value: 20, want: 68},
+		{desc: "mph", convert: kphToMPH, value: 100, want: 62.137},
+		{desc: "inhg", convert: mbarToInHg, value: 1013.25, want: 29.921},
+		{desc: "inches", convert: mmToInches, value: 25.4, want: 1},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.convert(tc.value); math.Abs(got-tc.want) > 0.001 {
+				t.Errorf("got %.4f, want %.3f", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHomecoachAccountUnits(t *testing.T) {
+	log := logrus.New()
+
+	// The account uses fahrenheit, but millibar for the pressure.
+	const data = `{
+	"body": {
+		"devices": [
+			{
+				"_id": "70:ee:50:00:00:10",
+				"type": "NHC",
+				"station_name": "Bedroom",
+				"dashboard_data": {"time_utc": 1700000000, "Temperature": 20.5, "Pressure": 1012.4}
+			}
+		],
+		"user": {
+			"administrative": {"unit": 1, "windunit": 1, "pressureunit": 0}
+		}
+	}
+}`
+
+	var homecoach HomecoachResponse
+	if err := json.Unmarshal([]byte(data), &homecoach); err != nil {
+		t.Fatalf("error decoding test data: %s", err)
+	}
+
+	store := NewStore(log, nil, func() (*HomecoachResponse, error) {
+		return &homecoach, nil
+	}, nil, time.Hour)
+	store.RefreshHomecoach()
+
+	collectorV1 := NewHomecoachCollector(log, store, time.Hour, StaleDrop, UnitsAccount)
+	collectorV1.clock = func() time.Time {
+		return testWeatherTime
+	}
+	collectorV2 := UnifiedCollector(log, store, time.Hour, StaleDrop, false, UnitsAccount)
+	collectorV2.clock = func() time.Time {
+		return testWeatherTime
+	}
+
+	tt := []struct {
+		collector prometheus.Collector
+		metric    string
+		want      int
+	}{
+		{collector: collectorV1, metric: "netatmo_homecoach_temperature_fahrenheit", want: 1},
+		{collector: collectorV1, metric: "netatmo_homecoach_pressure_inhg", want: 0},
+		{collector: collectorV2, metric: "netatmo_sensor_temperature_fahrenheit", want: 1},
+		{collector: collectorV2, metric: "netatmo_sensor_pressure_inhg", want: 0},
+	}
+
+	for _, tc := range tt {
+		if got := testutil.CollectAndCount(tc.collector, tc.metric); got != tc.want {
+			t.Errorf("got %d series of %s, want %d", got, tc.metric, tc.want)
+		}
+	}
+}
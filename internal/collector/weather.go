@@ -1,12 +1,16 @@
 package collector
 
 import (
-	"sync"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"time"
 
 	netatmo "github.com/exzz/netatmo-api-go"
+	"github.com/marc825/netatmo-exporter/v2/internal/api"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
 )
 
 var (
@@ -105,34 +109,212 @@ var (
 		"RF signal strength (90: lowest, 60: highest)",
 		weatherLabels,
 		nil)
+
+	minTempDesc = prometheus.NewDesc(
+		sensorPrefix+"min_temperature_celsius",
+		"Minimum temperature of the current day in celsius",
+		weatherLabels,
+		nil)
+	maxTempDesc = prometheus.NewDesc(
+		sensorPrefix+"max_temperature_celsius",
+		"Maximum temperature of the current day in celsius",
+		weatherLabels,
+		nil)
+	minTempTimeDesc = prometheus.NewDesc(
+		sensorPrefix+"min_temperature_time",
+		"Time of the minimum temperature of the current day",
+		weatherLabels,
+		nil)
+	maxTempTimeDesc = prometheus.NewDesc(
+		sensorPrefix+"max_temperature_time",
+		"Time of the maximum temperature of the current day",
+		weatherLabels,
+		nil)
+
+	absolutePressureDesc = prometheus.NewDesc(
+		sensorPrefix+"absolute_pressure_mb",
+		"Absolute atmospheric pressure measurement in millibar, not reduced to sea level",
+		weatherLabels,
+		nil)
+
+	tempFahrenheitDesc = prometheus.NewDesc(
+		sensorPrefix+"temperature_fahrenheit",
+		"Temperature measurement in fahrenheit",
+		weatherLabels,
+		nil)
+	pressureInHgDesc = prometheus.NewDesc(
+		sensorPrefix+"pressure_inhg",
+		"Atmospheric pressure measurement in inches of mercury",
+		weatherLabels,
+		nil)
+	windStrengthMPHDesc = prometheus.NewDesc(
+		sensorPrefix+"wind_strength_mph",
+		"Wind strength in miles per hour",
+		weatherLabels,
+		nil)
+	rainInchesDesc = prometheus.NewDesc(
+		sensorPrefix+"rain_amount_inches",
+		"Rain amount in inches",
+		weatherLabels,
+		nil)
+
+	staleDesc = prometheus.NewDesc(
+		sensorPrefix+"stale",
+		"One if the latest measurement is older than the stale threshold",
+		weatherLabels,
+		nil)
 )
 
 // WeatherReadFunction defines the interface for reading from the Netatmo API.
-type WeatherReadFunction func() (*netatmo.DeviceCollection, error)
+type WeatherReadFunction func() (*WeatherResponse, error)
+
+// WeatherResponse contains the weather station data. It extends the devices decoded by netatmo-api-go
+// with the values the library does not decode.
+type WeatherResponse struct {
+	netatmo.DeviceCollection
+	// Details contains the additional values of every station and module by ID.
+	Details map[string]WeatherDetails
+	// Administrative contains the preferences of the account.
+	Administrative Administrative
+}
+
+// WeatherDetails contains the values of a weather station or module which are not decoded by netatmo-api-go.
+type WeatherDetails struct {
+	ID            string                  `json:"_id"`
+	Firmware      *int                    `json:"firmware"`
+	DateSetup     int64                   `json:"date_setup"`
+	LastSetup     int64                   `json:"last_setup"`
+	LastUpgrade   int64                   `json:"last_upgrade"`
+	Place         Place                   `json:"place"`
+	DashboardData WeatherDashboardDetails `json:"dashboard_data"`
+
+	// Reachable is only set if the API reported the reachability of the device.
+	Reachable *bool `json:"reachable"`
+	// LastStatusStore is only set for stations, LastSeen and LastMessage only for modules.
+	LastStatusStore int64 `json:"last_status_store"`
+	LastSeen        int64 `json:"last_seen"`
+	LastMessage     int64 `json:"last_message"`
+
+	// BatteryVP is the battery voltage of a module in millivolts.
+	BatteryVP *int `json:"battery_vp"`
+}
+
+// WeatherDashboardDetails contains the dashboard values which are not decoded by netatmo-api-go.
+type WeatherDashboardDetails struct {
+	MinTemp     *float64 `json:"min_temp"`
+	MaxTemp     *float64 `json:"max_temp"`
+	DateMinTemp *int64   `json:"date_min_temp"`
+	DateMaxTemp *int64   `json:"date_max_temp"`
+
+	MaxWindStrength     *float64 `json:"max_wind_str"`
+	DateMaxWindStrength *int64   `json:"date_max_wind_str"`
+
+	TempTrend     string `json:"temp_trend"`
+	PressureTrend string `json:"pressure_trend"`
+}
+
+// UnmarshalJSON decodes the devices and their details from a getstationsdata response.
+func (r *WeatherResponse) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.DeviceCollection); err != nil {
+		return err
+	}
+
+	var details struct {
+		Body struct {
+			Devices []struct {
+				WeatherDetails
+				Modules []WeatherDetails `json:"modules"`
+			} `json:"devices"`
+			User struct {
+				Administrative Administrative `json:"administrative"`
+			} `json:"user"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal(data, &details); err != nil {
+		return err
+	}
+
+	r.Administrative = details.Body.User.Administrative
+
+	r.Details = make(map[string]WeatherDetails)
+	for _, dev := range details.Body.Devices {
+		r.Details[dev.ID] = dev.WeatherDetails
+		for _, module := range dev.Modules {
+			r.Details[module.ID] = module
+		}
+	}
+
+	return nil
+}
+
+// DeviceDetails returns the details of the station or module with the given ID.
+func (r *WeatherResponse) DeviceDetails(id string) WeatherDetails {
+	return r.Details[id]
+}
+
+// FetchStationsData reads the weather station data from the Netatmo API. If favorites is set, the stations
+// marked as favorite by the user are included as read-only devices.
+func FetchStationsData(client *http.Client, favorites bool) (*WeatherResponse, error) {
+	stationsURL := "https://api.netatmo.com/api/getstationsdata"
+	if favorites {
+		stationsURL += "?get_favorites=true"
+	}
+
+	req, err := http.NewRequest(http.MethodGet, stationsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating getstationsdata request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing getstationsdata request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getstationsdata request failed: %w", api.ResponseError(resp))
+	}
+
+	var result WeatherResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		api.ReportDecodeError(resp)
+		return nil, fmt.Errorf("decoding getstationsdata response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// NewWeatherReadFunction creates a reader function for weather station data, optionally including favorite stations.
+func NewWeatherReadFunction(getCurrentToken func() (*oauth2.Token, error), apiClient *http.Client, favorites bool) WeatherReadFunction {
+	return func() (*WeatherResponse, error) {
+		httpClient, err := api.AuthenticatedClient(getCurrentToken, apiClient)
+		if err != nil {
+			return nil, err
+		}
+		return FetchStationsData(httpClient, favorites)
+	}
+}
 
 // WeatherCollector is a Prometheus collector for Netatmo sensor values.
 type WeatherCollector struct {
-	Log             logrus.FieldLogger
-	RefreshInterval time.Duration
-	StaleThreshold  time.Duration
-	ReadFunction    WeatherReadFunction
-	clock           func() time.Time
-
-	lastRefresh         time.Time
-	lastRefreshError    error
-	lastRefreshDuration time.Duration
-	cacheLock           sync.RWMutex
-	cacheTimestamp      time.Time
-	cachedData          *netatmo.DeviceCollection
+	Log            logrus.FieldLogger
+	StaleThreshold time.Duration
+	StalePolicy    StalePolicy
+	Units          UnitSystem
+	Store          *Store
+	clock          func() time.Time
 }
 
-func NewWeatherReadFunction(log *logrus.Logger, readFunction WeatherReadFunction, refreshInterval, staleDuration time.Duration) *WeatherCollector {
+// NewWeatherCollector creates a WeatherCollector which reads the weather station data from the store.
+// Depending on the unit system, measurements are exported in imperial units as well.
+func NewWeatherCollector(log logrus.FieldLogger, store *Store, staleDuration time.Duration, stalePolicy StalePolicy, units UnitSystem) *WeatherCollector {
 	return &WeatherCollector{
-		Log:             log,
-		RefreshInterval: refreshInterval,
-		StaleThreshold:  staleDuration,
-		ReadFunction:    readFunction,
-		clock:           time.Now,
+		Log:            log,
+		StaleThreshold: staleDuration,
+		StalePolicy:    stalePolicy,
+		Units:          units,
+		Store:          store,
+		clock:          time.Now,
 	}
 }
 
@@ -155,68 +337,49 @@ func (c *WeatherCollector) Describe(dChan chan<- *prometheus.Desc) {
 	dChan <- batteryDesc
 	dChan <- wifiDesc
 	dChan <- rfDesc
+	dChan <- minTempDesc
+	dChan <- maxTempDesc
+	dChan <- minTempTimeDesc
+	dChan <- maxTempTimeDesc
+	dChan <- absolutePressureDesc
+	dChan <- tempFahrenheitDesc
+	dChan <- pressureInHgDesc
+	dChan <- windStrengthMPHDesc
+	dChan <- rainInchesDesc
+	dChan <- staleDesc
 }
 
 // Collect implements prometheus.Collector
 func (c *WeatherCollector) Collect(mChan chan<- prometheus.Metric) {
-	now := c.clock()
-	if now.Sub(c.lastRefresh) >= c.RefreshInterval {
-		go c.RefreshData(now)
-	}
+	snapshot := c.Store.Weather()
 
 	upValue := 1.0
-	if c.lastRefresh.IsZero() || c.lastRefreshError != nil {
+	if !snapshot.Up() {
 		upValue = 0
 	}
 	sendMetric(c.Log, mChan, netatmoUpDesc, prometheus.GaugeValue, upValue)
-	sendMetric(c.Log, mChan, refreshIntervalDesc, prometheus.GaugeValue, c.RefreshInterval.Seconds())
-	sendMetric(c.Log, mChan, refreshTimestampDesc, prometheus.GaugeValue, convertTime(c.lastRefresh))
-	sendMetric(c.Log, mChan, refreshDurationDesc, prometheus.GaugeValue, c.lastRefreshDuration.Seconds())
-
-	c.cacheLock.RLock()
-	defer c.cacheLock.RUnlock()
-
-	sendMetric(c.Log, mChan, cacheTimestampDesc, prometheus.GaugeValue, convertTime(c.cacheTimestamp))
-	if c.cachedData != nil {
-		for _, dev := range c.cachedData.Devices() {
+	sendMetric(c.Log, mChan, refreshIntervalDesc, prometheus.GaugeValue, c.Store.RefreshInterval().Seconds())
+	sendMetric(c.Log, mChan, refreshTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.LastRefresh))
+	sendMetric(c.Log, mChan, refreshDurationDesc, prometheus.GaugeValue, snapshot.LastRefreshDuration.Seconds())
+	sendMetric(c.Log, mChan, cacheTimestampDesc, prometheus.GaugeValue, convertTime(snapshot.CacheTimestamp))
+
+	if snapshot.Data != nil {
+		units := c.Units.imperial(snapshot.Data.Administrative)
+		for _, dev := range snapshot.Data.Devices() {
 			homeName := dev.HomeName
 			stationName := dev.StationName //nolint: staticcheck
-			c.collectData(mChan, dev, stationName, homeName)
+			owned := !dev.ReadOnly
+			c.collectData(mChan, dev, snapshot.Data.DeviceDetails(dev.ID), units, stationName, homeName, owned)
 
 			for _, module := range dev.LinkedModules {
-				c.collectData(mChan, module, stationName, homeName)
+				c.collectData(mChan, module, snapshot.Data.DeviceDetails(module.ID), units, stationName, homeName, owned)
 			}
 		}
 	}
 }
 
-// RefreshData causes the collector to try to refresh the cached data.
-func (c *WeatherCollector) RefreshData(now time.Time) {
-	c.Log.Debugf("Refreshing data. Time since last refresh: %s", now.Sub(c.lastRefresh))
-	c.lastRefresh = now
-
-	defer func(start time.Time) {
-		c.lastRefreshDuration = c.clock().Sub(start)
-	}(c.clock())
-
-	devices, err := c.ReadFunction()
-	c.lastRefreshError = err
-	if err != nil {
-		c.Log.Errorf("Error during refresh: %s", err)
-		return
-	}
-
-	c.cacheLock.Lock()
-	defer c.cacheLock.Unlock()
-	c.cacheTimestamp = now
-	c.cachedData = devices
-}
-
-func (c *WeatherCollector) collectData(ch chan<- prometheus.Metric, device *netatmo.Device, stationName, homeName string) {
-	moduleName := device.ModuleName
-	if moduleName == "" {
-		moduleName = "id-" + device.ID
-	}
+func (c *WeatherCollector) collectData(ch chan<- prometheus.Metric, device *netatmo.Device, details WeatherDetails, units imperialUnits, stationName, homeName string, owned bool) {
+	moduleName := weatherModuleName(device.ModuleName, device.ID)
 
 	data := device.DashboardData
 
@@ -227,15 +390,28 @@ func (c *WeatherCollector) collectData(ch chan<- prometheus.Metric, device *neta
 
 	date := time.Unix(*data.LastMeasure, 0)
 	dataAge := c.clock().Sub(date)
-	if dataAge > c.StaleThreshold {
+	stale := dataAge > c.StaleThreshold
+	if stale {
 		c.Log.Debugf("Data is stale for %s: %s > %s", moduleName, dataAge, c.StaleThreshold)
-		return
+	}
+
+	switch c.StalePolicy {
+	case StaleKeep:
+	case StaleMark:
+		sendMetric(c.Log, ch, staleDesc, prometheus.GaugeValue, boolValue(stale), moduleName, stationName, homeName)
+	default:
+		if stale {
+			return
+		}
 	}
 
 	sendMetric(c.Log, ch, updatedDesc, prometheus.GaugeValue, float64(date.UTC().Unix()), moduleName, stationName, homeName)
 
 	if data.Temperature != nil {
 		sendMetric(c.Log, ch, tempDesc, prometheus.GaugeValue, float64(*data.Temperature), moduleName, stationName, homeName)
+		if units.temperature {
+			sendMetric(c.Log, ch, tempFahrenheitDesc, prometheus.GaugeValue, celsiusToFahrenheit(float64(*data.Temperature)), moduleName, stationName, homeName)
+		}
 	}
 
 	if data.Humidity != nil {
@@ -252,10 +428,30 @@ func (c *WeatherCollector) collectData(ch chan<- prometheus.Metric, device *neta
 
 	if data.Pressure != nil {
 		sendMetric(c.Log, ch, pressureDesc, prometheus.GaugeValue, float64(*data.Pressure), moduleName, stationName, homeName)
+		if units.pressure {
+			sendMetric(c.Log, ch, pressureInHgDesc, prometheus.GaugeValue, mbarToInHg(float64(*data.Pressure)), moduleName, stationName, homeName)
+		}
+	}
+
+	if data.AbsolutePressure != nil {
+		sendMetric(c.Log, ch, absolutePressureDesc, prometheus.GaugeValue, float64(*data.AbsolutePressure), moduleName, stationName, homeName)
+	}
+
+	if dd := details.DashboardData; dd.MinTemp != nil && dd.DateMinTemp != nil {
+		sendMetric(c.Log, ch, minTempDesc, prometheus.GaugeValue, *dd.MinTemp, moduleName, stationName, homeName)
+		sendMetric(c.Log, ch, minTempTimeDesc, prometheus.GaugeValue, float64(*dd.DateMinTemp), moduleName, stationName, homeName)
+	}
+
+	if dd := details.DashboardData; dd.MaxTemp != nil && dd.DateMaxTemp != nil {
+		sendMetric(c.Log, ch, maxTempDesc, prometheus.GaugeValue, *dd.MaxTemp, moduleName, stationName, homeName)
+		sendMetric(c.Log, ch, maxTempTimeDesc, prometheus.GaugeValue, float64(*dd.DateMaxTemp), moduleName, stationName, homeName)
 	}
 
 	if data.WindStrength != nil {
 		sendMetric(c.Log, ch, windStrengthDesc, prometheus.GaugeValue, float64(*data.WindStrength), moduleName, stationName, homeName)
+		if units.windStrength {
+			sendMetric(c.Log, ch, windStrengthMPHDesc, prometheus.GaugeValue, kphToMPH(float64(*data.WindStrength)), moduleName, stationName, homeName)
+		}
 	}
 
 	if data.WindAngle != nil {
@@ -264,6 +460,14 @@ func (c *WeatherCollector) collectData(ch chan<- prometheus.Metric, device *neta
 
 	if data.Rain != nil {
 		sendMetric(c.Log, ch, rainDesc, prometheus.GaugeValue, float64(*data.Rain), moduleName, stationName, homeName)
+		if units.temperature {
+			sendMetric(c.Log, ch, rainInchesDesc, prometheus.GaugeValue, mmToInches(float64(*data.Rain)), moduleName, stationName, homeName)
+		}
+	}
+
+	// Favorite stations only export their measurements.
+	if !owned {
+		return
 	}
 
 	if device.BatteryPercent != nil {
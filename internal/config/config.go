@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,11 +23,31 @@ const (
 	envVarLogLevel            = "NETATMO_LOG_LEVEL"
 	envVarRefreshInterval     = "NETATMO_REFRESH_INTERVAL"
 	envVarStaleDuration       = "NETATMO_AGE_STALE"
+	envVarStalePolicy         = "NETATMO_STALE_POLICY"
+	envVarUnits               = "NETATMO_UNITS"
 	envVarNetatmoClientID     = "NETATMO_CLIENT_ID"
 	envVarNetatmoClientSecret = "NETATMO_CLIENT_SECRET"
 	envVarEnableHomeCoach     = "NETATMO_ENABLE_HOMECOACH"
 	envVarEnableWeather       = "NETATMO_ENABLE_WEATHER"
+	envVarEnableEnergy        = "NETATMO_ENABLE_ENERGY"
+	envVarEnableSecurity      = "NETATMO_ENABLE_SECURITY"
+	envVarEnableDetector      = "NETATMO_ENABLE_DETECTOR"
+	envVarEnableHomeControl   = "NETATMO_ENABLE_HOMECONTROL"
 	envVarEnableGoMetrics     = "NETATMO_ENABLE_GO_METRICS"
+	envVarWeatherFavorites    = "NETATMO_WEATHER_FAVORITES"
+	envVarDerivedMetrics      = "NETATMO_DERIVED_METRICS"
+	envVarOutdoorPairing      = "NETATMO_OUTDOOR_PAIRING"
+	envVarHomecoachHomes      = "NETATMO_HOMECOACH_HOMES"
+	envVarBackfill            = "NETATMO_BACKFILL"
+	envVarBackfillDirectory   = "NETATMO_BACKFILL_DIRECTORY"
+	envVarBackfillRemoteWrite = "NETATMO_BACKFILL_REMOTE_WRITE_URL"
+	envVarPublicArea          = "NETATMO_PUBLIC_AREA"
+	envVarPublicInterval      = "NETATMO_PUBLIC_REFRESH_INTERVAL"
+	envVarPublicStations      = "NETATMO_PUBLIC_STATIONS"
+	envVarAccounts            = "NETATMO_ACCOUNTS"
+	envVarAccountClientIDs    = "NETATMO_ACCOUNT_CLIENT_IDS"
+	envVarAccountSecrets      = "NETATMO_ACCOUNT_CLIENT_SECRETS"
+	envVarAccountTokenFiles   = "NETATMO_ACCOUNT_TOKEN_FILES"
 
 	flagListenAddress       = "addr"
 	flagExternalURL         = "external-url"
@@ -33,32 +56,88 @@ const (
 	flagLogLevel            = "log-level"
 	flagRefreshInterval     = "refresh-interval"
 	flagStaleDuration       = "age-stale"
+	flagStalePolicy         = "stale-policy"
+	flagUnits               = "units"
 	flagNetatmoClientID     = "client-id"
 	flagNetatmoClientSecret = "client-secret"
 	flagEnableHomeCoach     = "enable-homecoach"
 	flagEnableWeather       = "enable-weather"
+	flagEnableEnergy        = "enable-energy"
+	flagEnableSecurity      = "enable-security"
+	flagEnableDetector      = "enable-detector"
+	flagEnableHomeControl   = "enable-homecontrol"
 	flagEnableGoMetrics     = "enable-go-metrics"
+	flagWeatherFavorites    = "weather-favorites"
+	flagDerivedMetrics      = "derived-metrics"
+	flagOutdoorPairing      = "outdoor-pairing"
+	flagHomecoachHomes      = "homecoach-homes"
+	flagBackfill            = "backfill"
+	flagBackfillDirectory   = "backfill-directory"
+	flagBackfillRemoteWrite = "backfill-remote-write-url"
+	flagPublicArea          = "public-area"
+	flagPublicInterval      = "public-refresh-interval"
+	flagPublicStations      = "public-stations"
+	flagAccounts            = "accounts"
+	flagAccountClientIDs    = "account-client-ids"
+	flagAccountSecrets      = "account-client-secrets"
+	flagAccountTokenFiles   = "account-token-files"
 
 	defaultRefreshInterval = 8 * time.Minute
 	defaultStaleDuration   = 60 * time.Minute
+	defaultPublicInterval  = 30 * time.Minute
+
+	// StalePolicyDrop stops exporting the measurements of stale devices.
+	StalePolicyDrop = "drop"
+	// StalePolicyKeep keeps exporting the last measurements of stale devices.
+	StalePolicyKeep = "keep"
+	// StalePolicyMark keeps exporting the last measurements of stale devices and marks them as stale.
+	StalePolicyMark = "mark"
+
+	// UnitsMetric only exports metric units.
+	UnitsMetric = "metric"
+	// UnitsImperial exports imperial units in addition to the metric units.
+	UnitsImperial = "imperial"
+	// UnitsAccount exports the imperial units selected in the Netatmo account in addition to the metric units.
+	UnitsAccount = "account"
+
+	// BackfillOpenMetrics writes backfilled measurements into OpenMetrics files.
+	BackfillOpenMetrics = "openmetrics"
+	// BackfillRemoteWrite sends backfilled measurements to a Prometheus remote-write endpoint.
+	BackfillRemoteWrite = "remote-write"
 )
 
 var (
 	defaultConfig = Config{
-		Addr:            ":9210",
-		LogLevel:        logLevel(logrus.InfoLevel),
-		RefreshInterval: defaultRefreshInterval,
-		StaleDuration:   defaultStaleDuration,
-		EnableHomecoach: true,
-		EnableWeather:   true,
-		EnableGoMetrics: false, // Standard: Go-Metriken ausblenden
-	}
-
-	errNoBinaryName          = errors.New("need the binary name as first argument")
-	errNoListenAddress       = errors.New("no listen address")
-	errNoTokenFile           = errors.New("need a token file to save the token")
-	errNoNetatmoClientID     = errors.New("need a NetAtmo client ID")
-	errNoNetatmoClientSecret = errors.New("need a NetAtmo client secret")
+		Addr:              ":9210",
+		LogLevel:          logLevel(logrus.InfoLevel),
+		RefreshInterval:   defaultRefreshInterval,
+		StaleDuration:     defaultStaleDuration,
+		StalePolicy:       StalePolicyDrop,
+		Units:             UnitsMetric,
+		EnableHomecoach:   true,
+		EnableWeather:     true,
+		EnableEnergy:      false,
+		EnableSecurity:    false,
+		EnableDetector:    false,
+		EnableHomeControl: false,
+		EnableGoMetrics:   false, // Standard: Go-Metriken ausblenden
+		PublicInterval:    defaultPublicInterval,
+	}
+
+	errNoBinaryName           = errors.New("need the binary name as first argument")
+	errNoListenAddress        = errors.New("no listen address")
+	errNoTokenFile            = errors.New("need a token file to save the token")
+	errNoNetatmoClientID      = errors.New("need a NetAtmo client ID")
+	errNoNetatmoClientSecret  = errors.New("need a NetAtmo client secret")
+	errNoRemoteWriteURL       = errors.New("need a remote-write URL for backfilling")
+	errInvalidRefreshInterval = errors.New("refresh interval needs to be positive")
+	errInvalidPublicInterval  = errors.New("public refresh interval needs to be positive")
+	errInvalidPublicArea      = errors.New("public area needs to be \"lat_sw,lon_sw,lat_ne,lon_ne\" with the south-west corner below the north-east corner")
+	errInvalidAccountName     = errors.New("account names may only contain letters, digits, \"-\" and \"_\"")
+	errDuplicateAccount       = errors.New("account names need to be unique")
+	errUnknownAccount         = errors.New("account options need to refer to a configured account")
+
+	accountNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
 )
 
 type logLevel logrus.Level
@@ -90,11 +169,48 @@ type Config struct {
 	LogLevel        logLevel
 	RefreshInterval time.Duration
 	StaleDuration   time.Duration
+	StalePolicy     string
+	Units           string
 	Netatmo         netatmo.Config
 	// Enable or disable individual collectors
-	EnableHomecoach bool
-	EnableWeather   bool
-	EnableGoMetrics bool // Go Runtime Metriken (GC, Memory, Goroutines)
+	EnableHomecoach   bool
+	EnableWeather     bool
+	EnableEnergy      bool
+	EnableSecurity    bool
+	EnableDetector    bool
+	EnableHomeControl bool
+	EnableGoMetrics   bool // Go Runtime Metriken (GC, Memory, Goroutines)
+	// Include the favorite weather stations of the user
+	WeatherFavorites bool
+	// Export metrics calculated from the measurements, like the dew point
+	DerivedMetrics bool
+	// Outdoor module used for the indoor climate advice by station or HomeCoach ID
+	OutdoorPairing map[string]string
+	// Home of HomeCoach devices by ID, used for the home label on /metrics/v2
+	HomecoachHomes map[string]string
+	// Backfilling of measurements missed while the exporter could not reach the API
+	Backfill               string
+	BackfillDirectory      string
+	BackfillRemoteWriteURL string
+	// Public weather stations in an area, given as lat_sw, lon_sw, lat_ne, lon_ne. Disabled if empty.
+	PublicArea     []float64
+	PublicInterval time.Duration
+	PublicStations bool
+	// Names of multiple Netatmo accounts and their options by account name. Only the default
+	// client and token file are used if no account names are set.
+	AccountNames         []string
+	AccountClientIDs     map[string]string
+	AccountClientSecrets map[string]string
+	AccountTokenFiles    map[string]string
+	// Accounts contains the resolved configuration of every account, empty if no account names are set.
+	Accounts []Account
+}
+
+// Account contains the configuration of a single Netatmo account.
+type Account struct {
+	Name      string
+	Netatmo   netatmo.Config
+	TokenFile string
 }
 
 // Parse takes the arguments and environment variables provided and creates the Config from that.
@@ -112,12 +228,32 @@ func Parse(args []string, getEnv func(string) string) (Config, error) {
 	flagSet.BoolVar(&cfg.DebugHandlers, flagDebugHandlers, cfg.DebugHandlers, "Enables debugging HTTP handlers.")
 	flagSet.Var(&cfg.LogLevel, flagLogLevel, "Sets the minimum level output through logging.")
 	flagSet.DurationVar(&cfg.RefreshInterval, flagRefreshInterval, cfg.RefreshInterval, "Time interval used for internal caching of NetAtmo sensor data.")
-	flagSet.DurationVar(&cfg.StaleDuration, flagStaleDuration, cfg.StaleDuration, "Data age to consider as stale. Stale data does not create metrics anymore.")
+	flagSet.DurationVar(&cfg.StaleDuration, flagStaleDuration, cfg.StaleDuration, "Data age to consider as stale. The handling of stale data is defined by the stale policy.")
+	flagSet.StringVar(&cfg.StalePolicy, flagStalePolicy, cfg.StalePolicy, "Handling of stale data. Can be \"drop\", \"keep\" or \"mark\".")
+	flagSet.StringVar(&cfg.Units, flagUnits, cfg.Units, "Units of the measurements. Can be \"metric\", \"imperial\" or \"account\", imperial units are exported in addition to the metric units.")
 	flagSet.StringVarP(&cfg.Netatmo.ClientID, flagNetatmoClientID, "i", cfg.Netatmo.ClientID, "Client ID for NetAtmo app.")
 	flagSet.StringVarP(&cfg.Netatmo.ClientSecret, flagNetatmoClientSecret, "s", cfg.Netatmo.ClientSecret, "Client secret for NetAtmo app.")
 	flagSet.BoolVar(&cfg.EnableHomecoach, flagEnableHomeCoach, cfg.EnableHomecoach, "Enable HomeCoach collector.")
 	flagSet.BoolVar(&cfg.EnableWeather, flagEnableWeather, cfg.EnableWeather, "Enable Weather station collector.")
+	flagSet.BoolVar(&cfg.EnableEnergy, flagEnableEnergy, cfg.EnableEnergy, "Enable Energy collector for thermostats and smart radiator valves.")
+	flagSet.BoolVar(&cfg.EnableSecurity, flagEnableSecurity, cfg.EnableSecurity, "Enable Security collector for cameras, doorbells and door/window tags.")
+	flagSet.BoolVar(&cfg.EnableDetector, flagEnableDetector, cfg.EnableDetector, "Enable Detector collector for smoke and carbon monoxide alarms.")
+	flagSet.BoolVar(&cfg.EnableHomeControl, flagEnableHomeControl, cfg.EnableHomeControl, "Enable Home+Control collector for plugs, switches and energy meters.")
 	flagSet.BoolVar(&cfg.EnableGoMetrics, flagEnableGoMetrics, cfg.EnableGoMetrics, "Enable Go runtime metrics (GC, memory, goroutines).")
+	flagSet.BoolVar(&cfg.WeatherFavorites, flagWeatherFavorites, cfg.WeatherFavorites, "Include the favorite weather stations of the user.")
+	flagSet.BoolVar(&cfg.DerivedMetrics, flagDerivedMetrics, cfg.DerivedMetrics, "Export metrics calculated from the measurements, like dew point and absolute humidity.")
+	flagSet.StringToStringVar(&cfg.OutdoorPairing, flagOutdoorPairing, cfg.OutdoorPairing, "Outdoor module used for the indoor climate advice of a station or HomeCoach as \"station_id=outdoor_module_id\".")
+	flagSet.StringToStringVar(&cfg.HomecoachHomes, flagHomecoachHomes, cfg.HomecoachHomes, "Home of a HomeCoach as \"device_id=home_name\". Overrides the home read from homesdata.")
+	flagSet.StringVar(&cfg.Backfill, flagBackfill, cfg.Backfill, "Backfill missed measurements. Can be \"openmetrics\" or \"remote-write\". Disabled if empty.")
+	flagSet.StringVar(&cfg.BackfillDirectory, flagBackfillDirectory, cfg.BackfillDirectory, "Directory for backfill OpenMetrics files. Defaults to the directory of the token file.")
+	flagSet.StringVar(&cfg.BackfillRemoteWriteURL, flagBackfillRemoteWrite, cfg.BackfillRemoteWriteURL, "Prometheus remote-write URL used for backfilling.")
+	flagSet.Float64SliceVar(&cfg.PublicArea, flagPublicArea, cfg.PublicArea, "Area of the public weather stations as \"lat_sw,lon_sw,lat_ne,lon_ne\". Disabled if empty.")
+	flagSet.DurationVar(&cfg.PublicInterval, flagPublicInterval, cfg.PublicInterval, "Time interval used for refreshing the public weather stations.")
+	flagSet.BoolVar(&cfg.PublicStations, flagPublicStations, cfg.PublicStations, "Export the measurements of every public weather station in addition to the area aggregates.")
+	flagSet.StringSliceVar(&cfg.AccountNames, flagAccounts, cfg.AccountNames, "Names of multiple Netatmo accounts. Metrics get an \"account\" label if set.")
+	flagSet.StringToStringVar(&cfg.AccountClientIDs, flagAccountClientIDs, cfg.AccountClientIDs, "Client ID of an account as \"account=client_id\". Defaults to the client ID.")
+	flagSet.StringToStringVar(&cfg.AccountClientSecrets, flagAccountSecrets, cfg.AccountClientSecrets, "Client secret of an account as \"account=client_secret\". Defaults to the client secret.")
+	flagSet.StringToStringVar(&cfg.AccountTokenFiles, flagAccountTokenFiles, cfg.AccountTokenFiles, "Token file of an account as \"account=path\". Defaults to the token file with the account name appended.")
 
 	if err := flagSet.Parse(args[1:]); err != nil {
 		return Config{}, err
@@ -148,21 +284,151 @@ func Parse(args []string, getEnv func(string) string) (Config, error) {
 		return Config{}, errNoTokenFile
 	}
 
-	if len(cfg.Netatmo.ClientID) == 0 {
-		return Config{}, errNoNetatmoClientID
+	if len(cfg.AccountNames) > 0 {
+		accounts, err := resolveAccounts(cfg)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.Accounts = accounts
+	} else {
+		if len(cfg.Netatmo.ClientID) == 0 {
+			return Config{}, errNoNetatmoClientID
+		}
+
+		if len(cfg.Netatmo.ClientSecret) == 0 {
+			return Config{}, errNoNetatmoClientSecret
+		}
 	}
 
-	if len(cfg.Netatmo.ClientSecret) == 0 {
-		return Config{}, errNoNetatmoClientSecret
+	if cfg.RefreshInterval <= 0 {
+		return Config{}, errInvalidRefreshInterval
 	}
 
 	if cfg.StaleDuration < cfg.RefreshInterval {
 		return Config{}, fmt.Errorf("stale duration smaller than refresh interval: %s < %s", cfg.StaleDuration, cfg.RefreshInterval)
 	}
 
+	switch cfg.StalePolicy {
+	case StalePolicyDrop, StalePolicyKeep, StalePolicyMark:
+	default:
+		return Config{}, fmt.Errorf("invalid stale policy: %q", cfg.StalePolicy)
+	}
+
+	switch cfg.Units {
+	case UnitsMetric, UnitsImperial, UnitsAccount:
+	default:
+		return Config{}, fmt.Errorf("invalid units: %q", cfg.Units)
+	}
+
+	switch cfg.Backfill {
+	case "":
+	case BackfillOpenMetrics:
+		if cfg.BackfillDirectory == "" {
+			cfg.BackfillDirectory = filepath.Dir(cfg.TokenFile)
+		}
+	case BackfillRemoteWrite:
+		if cfg.BackfillRemoteWriteURL == "" {
+			return Config{}, errNoRemoteWriteURL
+		}
+	default:
+		return Config{}, fmt.Errorf("invalid backfill mode: %q", cfg.Backfill)
+	}
+
+	if len(cfg.PublicArea) > 0 && !validArea(cfg.PublicArea) {
+		return Config{}, errInvalidPublicArea
+	}
+
+	if len(cfg.PublicArea) > 0 && cfg.PublicInterval <= 0 {
+		return Config{}, errInvalidPublicInterval
+	}
+
 	return cfg, nil
 }
 
+// resolveAccounts creates the configuration of every account, using the default client and token file for
+// options not set for an account.
+func resolveAccounts(cfg Config) ([]Account, error) {
+	names := make(map[string]bool, len(cfg.AccountNames))
+	for _, name := range cfg.AccountNames {
+		if !accountNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%w: %q", errInvalidAccountName, name)
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("%w: %q", errDuplicateAccount, name)
+		}
+		names[name] = true
+	}
+
+	for _, options := range []map[string]string{cfg.AccountClientIDs, cfg.AccountClientSecrets, cfg.AccountTokenFiles} {
+		for name := range options {
+			if !names[name] {
+				return nil, fmt.Errorf("%w: %q", errUnknownAccount, name)
+			}
+		}
+	}
+
+	accounts := make([]Account, 0, len(cfg.AccountNames))
+	for _, name := range cfg.AccountNames {
+		account := Account{
+			Name:      name,
+			Netatmo:   cfg.Netatmo,
+			TokenFile: accountTokenFile(cfg.TokenFile, name),
+		}
+
+		if clientID, ok := cfg.AccountClientIDs[name]; ok {
+			account.Netatmo.ClientID = clientID
+		}
+		if clientSecret, ok := cfg.AccountClientSecrets[name]; ok {
+			account.Netatmo.ClientSecret = clientSecret
+		}
+		if tokenFile, ok := cfg.AccountTokenFiles[name]; ok {
+			account.TokenFile = tokenFile
+		}
+
+		if len(account.Netatmo.ClientID) == 0 {
+			return nil, fmt.Errorf("%w for account %q", errNoNetatmoClientID, name)
+		}
+
+		if len(account.Netatmo.ClientSecret) == 0 {
+			return nil, fmt.Errorf("%w for account %q", errNoNetatmoClientSecret, name)
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+// accountTokenFile returns the default token file of an account, which has the account name appended to the
+// name of the token file, for example "token-office.json".
+func accountTokenFile(tokenFile, name string) string {
+	ext := filepath.Ext(tokenFile)
+
+	return strings.TrimSuffix(tokenFile, ext) + "-" + name + ext
+}
+
+// validArea checks that the area consists of the south-west and north-east corner of a bounding box.
+func validArea(area []float64) bool {
+	if len(area) != 4 {
+		return false
+	}
+
+	latSW, lonSW, latNE, lonNE := area[0], area[1], area[2], area[3]
+	for _, lat := range []float64{latSW, latNE} {
+		if lat < -90 || lat > 90 {
+			return false
+		}
+	}
+	for _, lon := range []float64{lonSW, lonNE} {
+		if lon < -180 || lon > 180 {
+			return false
+		}
+	}
+
+	return latSW < latNE && lonSW < lonNE
+}
+
 func applyEnvironment(cfg *Config, getenv func(string) string) error {
 	if envAddr := getenv(envVarListenAddress); envAddr != "" {
 		cfg.Addr = envAddr
@@ -204,6 +470,14 @@ func applyEnvironment(cfg *Config, getenv func(string) string) error {
 		cfg.StaleDuration = duration
 	}
 
+	if stalePolicy := getenv(envVarStalePolicy); stalePolicy != "" {
+		cfg.StalePolicy = stalePolicy
+	}
+
+	if units := getenv(envVarUnits); units != "" {
+		cfg.Units = units
+	}
+
 	if envClientID := getenv(envVarNetatmoClientID); envClientID != "" {
 		cfg.Netatmo.ClientID = envClientID
 	}
@@ -236,6 +510,54 @@ func applyEnvironment(cfg *Config, getenv func(string) string) error {
 		}
 	}
 
+	if envEnableEnergy := getenv(envVarEnableEnergy); envEnableEnergy != "" {
+		v := strings.ToLower(envEnableEnergy)
+		switch v {
+		case "true":
+			cfg.EnableEnergy = true
+		case "false":
+			cfg.EnableEnergy = false
+		default:
+			return fmt.Errorf("invalid value for %s: %s (expected 'true' or 'false')", envVarEnableEnergy, envEnableEnergy)
+		}
+	}
+
+	if envEnableSecurity := getenv(envVarEnableSecurity); envEnableSecurity != "" {
+		v := strings.ToLower(envEnableSecurity)
+		switch v {
+		case "true":
+			cfg.EnableSecurity = true
+		case "false":
+			cfg.EnableSecurity = false
+		default:
+			return fmt.Errorf("invalid value for %s: %s (expected 'true' or 'false')", envVarEnableSecurity, envEnableSecurity)
+		}
+	}
+
+	if envEnableDetector := getenv(envVarEnableDetector); envEnableDetector != "" {
+		v := strings.ToLower(envEnableDetector)
+		switch v {
+		case "true":
+			cfg.EnableDetector = true
+		case "false":
+			cfg.EnableDetector = false
+		default:
+			return fmt.Errorf("invalid value for %s: %s (expected 'true' or 'false')", envVarEnableDetector, envEnableDetector)
+		}
+	}
+
+	if envEnableHomeControl := getenv(envVarEnableHomeControl); envEnableHomeControl != "" {
+		v := strings.ToLower(envEnableHomeControl)
+		switch v {
+		case "true":
+			cfg.EnableHomeControl = true
+		case "false":
+			cfg.EnableHomeControl = false
+		default:
+			return fmt.Errorf("invalid value for %s: %s (expected 'true' or 'false')", envVarEnableHomeControl, envEnableHomeControl)
+		}
+	}
+
 	if envEnableGoMetrics := getenv(envVarEnableGoMetrics); envEnableGoMetrics != "" {
 		v := strings.ToLower(envEnableGoMetrics)
 		switch v {
@@ -248,5 +570,135 @@ func applyEnvironment(cfg *Config, getenv func(string) string) error {
 		}
 	}
 
+	if envWeatherFavorites := getenv(envVarWeatherFavorites); envWeatherFavorites != "" {
+		v := strings.ToLower(envWeatherFavorites)
+		switch v {
+		case "true":
+			cfg.WeatherFavorites = true
+		case "false":
+			cfg.WeatherFavorites = false
+		default:
+			return fmt.Errorf("invalid value for %s: %s (expected 'true' or 'false')", envVarWeatherFavorites, envWeatherFavorites)
+		}
+	}
+
+	if envDerivedMetrics := getenv(envVarDerivedMetrics); envDerivedMetrics != "" {
+		v := strings.ToLower(envDerivedMetrics)
+		switch v {
+		case "true":
+			cfg.DerivedMetrics = true
+		case "false":
+			cfg.DerivedMetrics = false
+		default:
+			return fmt.Errorf("invalid value for %s: %s (expected 'true' or 'false')", envVarDerivedMetrics, envDerivedMetrics)
+		}
+	}
+
+	if envOutdoorPairing := getenv(envVarOutdoorPairing); envOutdoorPairing != "" {
+		pairing, ok := parsePairs(envOutdoorPairing)
+		if !ok {
+			return fmt.Errorf("invalid value for %s: %s (expected 'station_id=outdoor_module_id')", envVarOutdoorPairing, envOutdoorPairing)
+		}
+		cfg.OutdoorPairing = pairing
+	}
+
+	if envHomecoachHomes := getenv(envVarHomecoachHomes); envHomecoachHomes != "" {
+		homes, ok := parsePairs(envHomecoachHomes)
+		if !ok {
+			return fmt.Errorf("invalid value for %s: %s (expected 'device_id=home_name')", envVarHomecoachHomes, envHomecoachHomes)
+		}
+		cfg.HomecoachHomes = homes
+	}
+
+	if backfill := getenv(envVarBackfill); backfill != "" {
+		cfg.Backfill = backfill
+	}
+
+	if backfillDirectory := getenv(envVarBackfillDirectory); backfillDirectory != "" {
+		cfg.BackfillDirectory = backfillDirectory
+	}
+
+	if remoteWriteURL := getenv(envVarBackfillRemoteWrite); remoteWriteURL != "" {
+		cfg.BackfillRemoteWriteURL = remoteWriteURL
+	}
+
+	if envPublicArea := getenv(envVarPublicArea); envPublicArea != "" {
+		var area []float64
+		for _, part := range strings.Split(envPublicArea, ",") {
+			value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %s", envVarPublicArea, envPublicArea)
+			}
+			area = append(area, value)
+		}
+		cfg.PublicArea = area
+	}
+
+	if envPublicInterval := getenv(envVarPublicInterval); envPublicInterval != "" {
+		duration, err := time.ParseDuration(envPublicInterval)
+		if err != nil {
+			return err
+		}
+		cfg.PublicInterval = duration
+	}
+
+	if envAccounts := getenv(envVarAccounts); envAccounts != "" {
+		var names []string
+		for _, name := range strings.Split(envAccounts, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+		cfg.AccountNames = names
+	}
+
+	if envClientIDs := getenv(envVarAccountClientIDs); envClientIDs != "" {
+		clientIDs, ok := parsePairs(envClientIDs)
+		if !ok {
+			return fmt.Errorf("invalid value for %s: %s (expected 'account=client_id')", envVarAccountClientIDs, envClientIDs)
+		}
+		cfg.AccountClientIDs = clientIDs
+	}
+
+	if envSecrets := getenv(envVarAccountSecrets); envSecrets != "" {
+		secrets, ok := parsePairs(envSecrets)
+		if !ok {
+			return fmt.Errorf("invalid value for %s (expected 'account=client_secret')", envVarAccountSecrets)
+		}
+		cfg.AccountClientSecrets = secrets
+	}
+
+	if envTokenFiles := getenv(envVarAccountTokenFiles); envTokenFiles != "" {
+		tokenFiles, ok := parsePairs(envTokenFiles)
+		if !ok {
+			return fmt.Errorf("invalid value for %s: %s (expected 'account=path')", envVarAccountTokenFiles, envTokenFiles)
+		}
+		cfg.AccountTokenFiles = tokenFiles
+	}
+
+	if envPublicStations := getenv(envVarPublicStations); envPublicStations != "" {
+		v := strings.ToLower(envPublicStations)
+		switch v {
+		case "true":
+			cfg.PublicStations = true
+		case "false":
+			cfg.PublicStations = false
+		default:
+			return fmt.Errorf("invalid value for %s: %s (expected 'true' or 'false')", envVarPublicStations, envPublicStations)
+		}
+	}
+
 	return nil
 }
+
+// parsePairs parses a comma-separated list of "key=value" pairs.
+func parsePairs(list string) (map[string]string, bool) {
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" || value == "" {
+			return nil, false
+		}
+		pairs[key] = value
+	}
+
+	return pairs, true
+}
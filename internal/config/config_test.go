@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -43,10 +44,15 @@ func TestParseConfig(t *testing.T) {
 				LogLevel:        logLevel(logrus.InfoLevel),
 				RefreshInterval: defaultRefreshInterval,
 				StaleDuration:   defaultStaleDuration,
+				StalePolicy:     StalePolicyDrop,
+				Units:           UnitsMetric,
 				Netatmo: netatmo.Config{
 					ClientID:     "id",
 					ClientSecret: "secret",
 				},
+				EnableHomecoach: true,
+				EnableWeather:   true,
+				PublicInterval:  defaultPublicInterval,
 			},
 			wantErr: nil,
 		},
@@ -62,8 +68,23 @@ func TestParseConfig(t *testing.T) {
 				envVarLogLevel:            "debug",
 				envVarRefreshInterval:     "5m",
 				envVarStaleDuration:       "10m",
+				envVarStalePolicy:         "mark",
+				envVarUnits:               "account",
 				envVarNetatmoClientID:     "id",
 				envVarNetatmoClientSecret: "secret",
+				envVarEnableEnergy:        "true",
+				envVarEnableSecurity:      "true",
+				envVarEnableDetector:      "true",
+				envVarEnableHomeControl:   "true",
+				envVarWeatherFavorites:    "true",
+				envVarDerivedMetrics:      "true",
+				envVarOutdoorPairing:      "70:ee:50:00:00:10=02:00:00:00:00:01",
+				envVarHomecoachHomes:      "70:ee:50:00:00:20=Home",
+				envVarBackfill:            "remote-write",
+				envVarBackfillRemoteWrite: "http://prometheus:9090/api/v1/write",
+				envVarPublicArea:          "48.1, 11.4, 48.2, 11.7",
+				envVarPublicInterval:      "1h",
+				envVarPublicStations:      "true",
 			},
 			wantConfig: Config{
 				Addr:            ":8080",
@@ -72,13 +93,164 @@ func TestParseConfig(t *testing.T) {
 				LogLevel:        logLevel(logrus.DebugLevel),
 				RefreshInterval: 5 * time.Minute,
 				StaleDuration:   10 * time.Minute,
+				StalePolicy:     StalePolicyMark,
+				Units:           UnitsAccount,
 				Netatmo: netatmo.Config{
 					ClientID:     "id",
 					ClientSecret: "secret",
 				},
+				EnableHomecoach:        true,
+				EnableWeather:          true,
+				EnableEnergy:           true,
+				EnableSecurity:         true,
+				EnableDetector:         true,
+				EnableHomeControl:      true,
+				WeatherFavorites:       true,
+				DerivedMetrics:         true,
+				OutdoorPairing:         map[string]string{"70:ee:50:00:00:10": "02:00:00:00:00:01"},
+				HomecoachHomes:         map[string]string{"70:ee:50:00:00:20": "Home"},
+				Backfill:               "remote-write",
+				BackfillRemoteWriteURL: "http://prometheus:9090/api/v1/write",
+				PublicArea:             []float64{48.1, 11.4, 48.2, 11.7},
+				PublicInterval:         time.Hour,
+				PublicStations:         true,
 			},
 			wantErr: nil,
 		},
+		{
+			name: "backfill directory from token file",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"/data/token.json",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagBackfill,
+				"openmetrics",
+			},
+			env: map[string]string{},
+			wantConfig: Config{
+				Addr:            defaultConfig.Addr,
+				ExternalURL:     "http://127.0.0.1:9210",
+				TokenFile:       "/data/token.json",
+				LogLevel:        logLevel(logrus.InfoLevel),
+				RefreshInterval: defaultRefreshInterval,
+				StaleDuration:   defaultStaleDuration,
+				StalePolicy:     StalePolicyDrop,
+				Units:           UnitsMetric,
+				Netatmo: netatmo.Config{
+					ClientID:     "id",
+					ClientSecret: "secret",
+				},
+				EnableHomecoach:   true,
+				EnableWeather:     true,
+				Backfill:          "openmetrics",
+				BackfillDirectory: "/data",
+				PublicInterval:    defaultPublicInterval,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "backfill without remote-write URL",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagBackfill,
+				"remote-write",
+			},
+			env:     map[string]string{},
+			wantErr: errNoRemoteWriteURL,
+		},
+		{
+			name: "zero refresh interval",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagRefreshInterval,
+				"0s",
+			},
+			env:     map[string]string{},
+			wantErr: errInvalidRefreshInterval,
+		},
+		{
+			name: "negative refresh interval",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+			},
+			env: map[string]string{
+				envVarRefreshInterval: "-5m",
+			},
+			wantErr: errInvalidRefreshInterval,
+		},
+		{
+			name: "public area with corners swapped",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagPublicArea,
+				"48.2,11.7,48.1,11.4",
+			},
+			env:     map[string]string{},
+			wantErr: errInvalidPublicArea,
+		},
+		{
+			name: "zero public refresh interval",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagPublicArea,
+				"48.1,11.4,48.2,11.7",
+				"--" + flagPublicInterval,
+				"0s",
+			},
+			env:     map[string]string{},
+			wantErr: errInvalidPublicInterval,
+		},
+		{
+			name: "negative public refresh interval",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+			},
+			env: map[string]string{
+				envVarPublicArea:     "48.1,11.4,48.2,11.7",
+				envVarPublicInterval: "-30m",
+			},
+			wantErr: errInvalidPublicInterval,
+		},
 		{
 			name: "no addr",
 			args: []string{
@@ -153,6 +325,116 @@ func TestParseConfig(t *testing.T) {
 			},
 			wantErr: errNoNetatmoClientSecret,
 		},
+		{
+			name: "accounts",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"/data/token.json",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagAccounts,
+				"office,home",
+				"--" + flagAccountTokenFiles,
+				"office=/data/office.json",
+			},
+			env: map[string]string{
+				envVarAccountClientIDs: "home=home-id",
+				envVarAccountSecrets:   "home=home-secret",
+			},
+			wantConfig: Config{
+				Addr:            defaultConfig.Addr,
+				ExternalURL:     "http://127.0.0.1:9210",
+				TokenFile:       "/data/token.json",
+				LogLevel:        logLevel(logrus.InfoLevel),
+				RefreshInterval: defaultRefreshInterval,
+				StaleDuration:   defaultStaleDuration,
+				StalePolicy:     StalePolicyDrop,
+				Units:           UnitsMetric,
+				Netatmo: netatmo.Config{
+					ClientID:     "id",
+					ClientSecret: "secret",
+				},
+				EnableHomecoach:      true,
+				EnableWeather:        true,
+				PublicInterval:       defaultPublicInterval,
+				AccountNames:         []string{"office", "home"},
+				AccountClientIDs:     map[string]string{"home": "home-id"},
+				AccountClientSecrets: map[string]string{"home": "home-secret"},
+				AccountTokenFiles:    map[string]string{"office": "/data/office.json"},
+				Accounts: []Account{
+					{
+						Name: "office",
+						Netatmo: netatmo.Config{
+							ClientID:     "id",
+							ClientSecret: "secret",
+						},
+						TokenFile: "/data/office.json",
+					},
+					{
+						Name: "home",
+						Netatmo: netatmo.Config{
+							ClientID:     "home-id",
+							ClientSecret: "home-secret",
+						},
+						TokenFile: "/data/token-home.json",
+					},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "accounts without shared client",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagAccounts,
+				"office,home",
+				"--" + flagAccountClientIDs,
+				"office=id",
+				"--" + flagAccountSecrets,
+				"office=secret",
+			},
+			env:     map[string]string{},
+			wantErr: errNoNetatmoClientID,
+		},
+		{
+			name: "invalid account name",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagAccounts,
+				"office/home",
+			},
+			env:     map[string]string{},
+			wantErr: errInvalidAccountName,
+		},
+		{
+			name: "option of unknown account",
+			args: []string{
+				"test-cmd",
+				"--" + flagTokenFile,
+				"token-file",
+				"--" + flagNetatmoClientID,
+				"id",
+				"--" + flagNetatmoClientSecret,
+				"secret",
+				"--" + flagAccounts,
+				"office",
+				"--" + flagAccountTokenFiles,
+				"home=home.json",
+			},
+			env:     map[string]string{},
+			wantErr: errUnknownAccount,
+		},
 	}
 
 	for _, tt := range tests {
@@ -167,7 +449,7 @@ func TestParseConfig(t *testing.T) {
 
 			config, err := Parse(tt.args, getenv)
 
-			if err != tt.wantErr {
+			if !errors.Is(err, tt.wantErr) {
 				t.Errorf("got error %q, want %q", err, tt.wantErr)
 			}
 
@@ -12,8 +12,8 @@ import (
 	"golang.org/x/oauth2"
 )
 
-// DebugNetatmoHandler erstellt einen Handler, der sowohl Weather- als auch HomeCoach-Daten anzeigt
-func DebugNetatmoHandler(log logrus.FieldLogger, weatherReadFunc func() (*netatmo.DeviceCollection, error), homecoachReadFunc func() (*collector.HomecoachResponse, error)) http.Handler {
+// DebugNetatmoHandler erstellt einen Handler, der die zwischengespeicherten Weather- und HomeCoach-Daten anzeigt
+func DebugNetatmoHandler(log logrus.FieldLogger, weatherReadFunc func() (*collector.WeatherResponse, error), homecoachReadFunc func() (*collector.HomecoachResponse, error)) http.Handler {
 	return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
 		// only allow GET
 		if r.Method != http.MethodGet {
@@ -31,7 +31,7 @@ func DebugNetatmoHandler(log logrus.FieldLogger, weatherReadFunc func() (*netatm
 
 		// Weather Data
 		if weatherReadFunc != nil {
-			var weatherData *netatmo.DeviceCollection
+			var weatherData *collector.WeatherResponse
 			weatherData, weatherErr = weatherReadFunc()
 			if weatherErr != nil {
 				errMsg := fmt.Sprintf("Error retrieving weather data: %s", weatherErr)
@@ -41,6 +41,7 @@ func DebugNetatmoHandler(log logrus.FieldLogger, weatherReadFunc func() (*netatm
 				// extract only the Devices
 				response.Weather = map[string]interface{}{
 					"devices": weatherData.Devices(),
+					"details": weatherData.Details,
 				}
 			} else {
 				response.Weather = map[string]interface{}{
@@ -16,15 +16,38 @@ const netatmoDevSite = "https://dev.netatmo.com/apps/"
 //go:embed home.html
 var homeHtml string
 
+// Account is a Netatmo account shown on the home page.
+type Account struct {
+	// Name is empty if the exporter only uses a single account.
+	Name string
+	// AuthPath is the path of the authentication handlers of the account, for example "/auth".
+	AuthPath string
+	// Token returns the current token of the account.
+	Token func() (*oauth2.Token, error)
+}
+
 type homeContext struct {
-	Valid          bool
-	Token          *oauth2.Token
+	Accounts       []accountContext
+	Scopes         []scopeContext
 	NetAtmoDevSite string
 }
 
+type scopeContext struct {
+	Name        string
+	Description string
+}
+
+type accountContext struct {
+	Name     string
+	AuthPath string
+	Valid    bool
+	Token    *oauth2.Token
+}
+
 // HomeHandler produces a simple website showing the exporter's status in a human-readable form.
-// It provides links to other information and help for authentication as well.
-func HomeHandler(tokenFunc func() (*oauth2.Token, error), log interface{ Warnf(string, ...interface{}) }) http.Handler {
+// It provides links to other information and help for authentication of every account as well, listing the
+// scopes needed by the enabled collectors.
+func HomeHandler(accounts []Account, features Features, log interface{ Warnf(string, ...interface{}) }) http.Handler {
 	homeTemplate, err := template.New("home.html").Funcs(map[string]any{
 		"remaining": remaining,
 	}).Parse(homeHtml)
@@ -32,25 +55,45 @@ func HomeHandler(tokenFunc func() (*oauth2.Token, error), log interface{ Warnf(s
 		panic(err)
 	}
 
-	return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
-		token, err := tokenFunc()
-		if err != nil {
-			// Log that token retrieval failed. We cannot distinguish between:
-			// - No token was ever set (expected)
-			// - Token was deleted (expected)
-			// - Token is expired and refresh failed (unexpected)
-			// API limitation: the underlying netatmo.Client returns nil token + error in all these cases
-			// Without API changes to return different error types, we log all cases equally.
-			log.Warnf("Token invalid or no token found: %v", err)
-			token = nil
-		}
+	var scopes []scopeContext
+	for _, scope := range buildScopes(features) {
+		scopes = append(scopes, scopeContext{
+			Name:        scope,
+			Description: scopeDescriptions[scope],
+		})
+	}
 
+	return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
 		context := homeContext{
-			Valid:          token != nil && token.Valid(),
-			Token:          token,
+			Scopes:         scopes,
 			NetAtmoDevSite: netatmoDevSite,
 		}
 
+		for _, account := range accounts {
+			token, err := account.Token()
+			if err != nil {
+				// Log that token retrieval failed. We cannot distinguish between:
+				// - No token was ever set (expected)
+				// - Token was deleted (expected)
+				// - Token is expired and refresh failed (unexpected)
+				// API limitation: the underlying netatmo.Client returns nil token + error in all these cases
+				// Without API changes to return different error types, we log all cases equally.
+				if account.Name != "" {
+					log.Warnf("Token of account %s invalid or no token found: %v", account.Name, err)
+				} else {
+					log.Warnf("Token invalid or no token found: %v", err)
+				}
+				token = nil
+			}
+
+			context.Accounts = append(context.Accounts, accountContext{
+				Name:     account.Name,
+				AuthPath: account.AuthPath,
+				Valid:    token != nil && token.Valid(),
+				Token:    token,
+			})
+		}
+
 		wr.Header().Set("Content-Type", "text/html")
 		if err := homeTemplate.Execute(wr, context); err != nil {
 			http.Error(wr, fmt.Sprintf("Error executing template: %s", err), http.StatusInternalServerError)
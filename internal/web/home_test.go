@@ -0,0 +1,63 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exzz/netatmo-api-go"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+func TestHomeHandlerAccounts(t *testing.T) {
+	accounts := []Account{
+		{
+			Name:     "office",
+			AuthPath: "/auth/office",
+			Token: func() (*oauth2.Token, error) {
+				return &oauth2.Token{
+					AccessToken: "access-token",
+					Expiry:      time.Now().Add(time.Hour),
+				}, nil
+			},
+		},
+		{
+			Name:     "home",
+			AuthPath: "/auth/home",
+			Token: func() (*oauth2.Token, error) {
+				return nil, netatmo.ErrNotAuthenticated
+			},
+		},
+	}
+
+	handler := HomeHandler(accounts, Features{Weather: true, Energy: true}, logrus.New())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"Account office",
+		`action="/auth/office/deletetoken"`,
+		"Account home",
+		`action="/auth/home/authorize"`,
+		`action="/auth/home/settoken"`,
+		"<b>read_station</b>",
+		"<b>read_thermostat</b>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body does not contain %q", want)
+		}
+	}
+
+	if strings.Contains(body, "read_homecoach") {
+		t.Error("body contains scope of disabled collector")
+	}
+}
@@ -12,13 +12,15 @@ import (
 	"golang.org/x/oauth2"
 )
 
-func AuthorizeHandler(externalURL string, client *netatmo.Client, enableWeather, enableHomecoach bool) http.HandlerFunc {
+// AuthorizeHandler redirects to the Netatmo authorization page. The authPath is the path of the
+// authentication handlers of the account, for example "/auth".
+func AuthorizeHandler(externalURL, authPath string, client *netatmo.Client, features Features) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		redirectURL := externalURL + "/auth/callback"
+		redirectURL := externalURL + authPath + "/callback"
 		baseAuthURL := client.AuthCodeURL(redirectURL, "definitelyrandom")
 
 		// Build the final auth URL with dynamic scopes
-		authURL := BuildAuthURL(baseAuthURL, enableWeather, enableHomecoach)
+		authURL := BuildAuthURL(baseAuthURL, features)
 
 		http.Redirect(w, r, authURL, http.StatusFound)
 	}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+// TargetDevicesFunc returns the IDs of the devices and modules of a probe target, which is either a home or a
+// station or device.
+type TargetDevicesFunc func(homeID, deviceID string) []string
+
+// ProbeHandler creates a handler which serves the V2 metrics of a single home or device, selected using the
+// "home" or "device_id" query parameter. The metrics are filtered from the cached metrics of the gatherer, so
+// probing does not cause requests to the Netatmo API.
+func ProbeHandler(log logrus.FieldLogger, gatherer prometheus.Gatherer, targetDevices TargetDevicesFunc) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(wr, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		homeID := query.Get("home")
+		deviceID := query.Get("device_id")
+		if (homeID == "") == (deviceID == "") {
+			http.Error(wr, "Exactly one of the parameters \"home\" or \"device_id\" is required", http.StatusBadRequest)
+			return
+		}
+
+		ids := make(map[string]bool)
+		for _, id := range targetDevices(homeID, deviceID) {
+			ids[id] = true
+		}
+
+		success := 0.0
+		if len(ids) > 0 {
+			success = 1
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "netatmo_probe_success",
+			Help: "Whether the probe target was found in the cached data",
+		}, func() float64 {
+			return success
+		}))
+
+		gatherers := prometheus.Gatherers{
+			probeGatherer{gatherer: gatherer, ids: ids},
+			registry,
+		}
+
+		promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{
+			ErrorLog: log,
+		}).ServeHTTP(wr, r)
+	})
+}
+
+// probeGatherer only returns the metrics of the gatherer belonging to one of the devices.
+type probeGatherer struct {
+	gatherer prometheus.Gatherer
+	ids      map[string]bool
+}
+
+func (g probeGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []*dto.MetricFamily
+	for _, family := range families {
+		var metrics []*dto.Metric
+		for _, metric := range family.GetMetric() {
+			if g.ids[deviceID(metric)] {
+				metrics = append(metrics, metric)
+			}
+		}
+
+		if len(metrics) > 0 {
+			family.Metric = metrics
+			filtered = append(filtered, family)
+		}
+	}
+
+	return filtered, nil
+}
+
+// deviceID returns the value of the "device_id" label of the metric.
+func deviceID(metric *dto.Metric) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == "device_id" {
+			return label.GetValue()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,109 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+func TestProbeHandler(t *testing.T) {
+	temperature := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "netatmo_sensor_temperature_celsius",
+		Help: "Temperature measurement in celsius",
+	}, []string{"device_id"})
+	temperature.WithLabelValues("70:ee:50:00:00:01").Set(21.5)
+	temperature.WithLabelValues("02:00:00:00:00:01").Set(8.5)
+	temperature.WithLabelValues("70:ee:50:00:00:02").Set(22)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(temperature)
+
+	targetDevices := func(homeID, deviceID string) []string {
+		switch {
+		case homeID == "home1":
+			return []string{"70:ee:50:00:00:01", "02:00:00:00:00:01"}
+		case deviceID == "70:ee:50:00:00:02":
+			return []string{"70:ee:50:00:00:02"}
+		default:
+			return nil
+		}
+	}
+
+	tests := []struct {
+		desc       string
+		query      string
+		wantStatus int
+		want       []string
+		wantNot    []string
+	}{
+		{
+			desc:       "home",
+			query:      "home=home1",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`netatmo_sensor_temperature_celsius{device_id="70:ee:50:00:00:01"} 21.5`,
+				`netatmo_sensor_temperature_celsius{device_id="02:00:00:00:00:01"} 8.5`,
+				"netatmo_probe_success 1",
+			},
+			wantNot: []string{`device_id="70:ee:50:00:00:02"`},
+		},
+		{
+			desc:       "device",
+			query:      "device_id=70:ee:50:00:00:02",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`netatmo_sensor_temperature_celsius{device_id="70:ee:50:00:00:02"} 22`,
+				"netatmo_probe_success 1",
+			},
+			wantNot: []string{`device_id="70:ee:50:00:00:01"`},
+		},
+		{
+			desc:       "unknown target",
+			query:      "home=home2",
+			wantStatus: http.StatusOK,
+			want:       []string{"netatmo_probe_success 0"},
+			wantNot:    []string{"netatmo_sensor_temperature_celsius"},
+		},
+		{
+			desc:       "no target",
+			query:      "",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			desc:       "both targets",
+			query:      "home=home1&device_id=70:ee:50:00:00:02",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			handler := ProbeHandler(logrus.New(), registry, targetDevices)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe?"+tc.query, nil))
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("got status %d, want %d", rec.Code, tc.wantStatus)
+			}
+
+			body := rec.Body.String()
+			for _, want := range tc.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("body does not contain %q", want)
+				}
+			}
+			for _, notWant := range tc.wantNot {
+				if strings.Contains(body, notWant) {
+					t.Errorf("body contains %q", notWant)
+				}
+			}
+		})
+	}
+}
@@ -5,29 +5,71 @@ import (
 	"strings"
 )
 
+// Features contains the enabled collectors, which determine the scopes requested during authorization.
+type Features struct {
+	Weather   bool
+	Homecoach bool
+	Energy    bool
+	Security  bool
+	Detector  bool
+	// HomeControl enables the Legrand Home+Control collector.
+	HomeControl bool
+	// Public enables the public weather stations collector, which uses the weather station scope.
+	Public bool
+}
+
 // BuildAuthURL builds the authorization URL with dynamic scopes based on enabled collectors.
 // This is a workaround for the netatmo-api-go library which hardcodes scopes to "read_station".
 // TODO: This can be removed once netatmo-api-go supports dynamic scopes natively.
-func BuildAuthURL(baseAuthURL string, enableWeather, enableHomecoach bool) string {
-	scopes := buildScopes(enableWeather, enableHomecoach)
+func BuildAuthURL(baseAuthURL string, features Features) string {
+	scopes := buildScopes(features)
 	return replaceScopes(baseAuthURL, scopes)
 }
 
 // buildScopes creates the list of OAuth scopes based on enabled collectors.
-func buildScopes(enableWeather, enableHomecoach bool) []string {
+func buildScopes(features Features) []string {
 	var scopes []string
 
-	if enableWeather {
+	if features.Weather || features.Public {
 		scopes = append(scopes, "read_station")
 	}
 
-	if enableHomecoach {
+	if features.Homecoach {
 		scopes = append(scopes, "read_homecoach")
 	}
 
+	if features.Energy {
+		scopes = append(scopes, "read_thermostat")
+	}
+
+	if features.Security {
+		scopes = append(scopes, "read_camera", "read_presence", "read_doorbell")
+	}
+
+	if features.Detector {
+		scopes = append(scopes, "read_smokedetector", "read_carbonmonoxidedetector")
+	}
+
+	if features.HomeControl {
+		scopes = append(scopes, "read_magellan")
+	}
+
 	return scopes
 }
 
+// scopeDescriptions contains the data read using each scope, as shown on the home page.
+var scopeDescriptions = map[string]string{
+	"read_station":                "for weather station data",
+	"read_homecoach":              "for HomeCoach data",
+	"read_thermostat":             "for thermostats and radiator valves (Energy)",
+	"read_camera":                 "for indoor cameras (Security)",
+	"read_presence":               "for outdoor cameras (Security)",
+	"read_doorbell":               "for doorbells (Security)",
+	"read_smokedetector":          "for smoke alarms (Detector)",
+	"read_carbonmonoxidedetector": "for carbon monoxide alarms (Detector)",
+	"read_magellan":               "for Legrand Home+Control devices",
+}
+
 // replaceScopes replaces the existing scope parameter in the authorization URL to prevent duplication of the 'read_station' scope.
 func replaceScopes(authURL string, scopes []string) string {
 	parsedURL, err := url.Parse(authURL)
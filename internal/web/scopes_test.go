@@ -0,0 +1,115 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildScopes(t *testing.T) {
+	tt := []struct {
+		desc       string
+		features   Features
+		wantScopes []string
+	}{
+		{
+			desc:       "none",
+			features:   Features{},
+			wantScopes: nil,
+		},
+		{
+			desc: "weather and homecoach",
+			features: Features{
+				Weather:   true,
+				Homecoach: true,
+			},
+			wantScopes: []string{"read_station", "read_homecoach"},
+		},
+		{
+			desc: "energy",
+			features: Features{
+				Energy: true,
+			},
+			wantScopes: []string{"read_thermostat"},
+		},
+		{
+			desc: "security",
+			features: Features{
+				Weather:  true,
+				Security: true,
+			},
+			wantScopes: []string{"read_station", "read_camera", "read_presence", "read_doorbell"},
+		},
+		{
+			desc: "detector",
+			features: Features{
+				Detector: true,
+			},
+			wantScopes: []string{"read_smokedetector", "read_carbonmonoxidedetector"},
+		},
+		{
+			desc: "home+control",
+			features: Features{
+				HomeControl: true,
+			},
+			wantScopes: []string{"read_magellan"},
+		},
+		{
+			desc: "public",
+			features: Features{
+				Public: true,
+			},
+			wantScopes: []string{"read_station"},
+		},
+		{
+			desc: "weather and public",
+			features: Features{
+				Weather: true,
+				Public:  true,
+			},
+			wantScopes: []string{"read_station"},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			scopes := buildScopes(tc.features)
+			if diff := cmp.Diff(tc.wantScopes, scopes); diff != "" {
+				t.Errorf("scopes differ: -want +got\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBuildAuthURL(t *testing.T) {
+	got := BuildAuthURL("https://api.netatmo.com/oauth2/authorize?client_id=id&scope=read_station&state=state", Features{
+		Weather: true,
+		Energy:  true,
+	})
+
+	want := "https://api.netatmo.com/oauth2/authorize?client_id=id&scope=read_station+read_thermostat&state=state"
+	if got != want {
+		t.Errorf("got URL %q, want %q", got, want)
+	}
+}
+
+func TestScopeDescriptions(t *testing.T) {
+	scopes := buildScopes(Features{
+		Weather:     true,
+		Homecoach:   true,
+		Energy:      true,
+		Security:    true,
+		Detector:    true,
+		HomeControl: true,
+	})
+
+	for _, scope := range scopes {
+		if scopeDescriptions[scope] == "" {
+			t.Errorf("scope %q has no description", scope)
+		}
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/exzz/netatmo-api-go"
 	"github.com/prometheus/client_golang/prometheus"
@@ -17,10 +16,9 @@ import (
 	"github.com/spf13/pflag"
 	"golang.org/x/oauth2"
 
-	"github.com/marc825/netatmo-exporter/v2/internal/collector"
+	"github.com/marc825/netatmo-exporter/v2/internal/api"
 	"github.com/marc825/netatmo-exporter/v2/internal/config"
 	"github.com/marc825/netatmo-exporter/v2/internal/logger"
-	"github.com/marc825/netatmo-exporter/v2/internal/token"
 	"github.com/marc825/netatmo-exporter/v2/internal/web"
 )
 
@@ -47,38 +45,35 @@ func main() {
 	log.Infof("netatmo-exporter %s (commit: %s)", Version, GitCommit)
 
 	// Validate that at least one collector is enabled
-	if !cfg.EnableWeather && !cfg.EnableHomecoach {
-		log.Fatal("At least one collector must be enabled. Remove NETATMO_ENABLE_WEATHER=false or NETATMO_ENABLE_HOMECOACH=false from the environment variables.")
-	}
-
-	// Netatmo API client
-	client := netatmo.NewClient(cfg.Netatmo, tokenUpdated(cfg.TokenFile))
-
-	// Load token from file if available
-	if cfg.TokenFile != "" {
-		token, err := loadToken(cfg.TokenFile)
-		switch {
-		case os.IsNotExist(err):
-			// no token file yet
-		case err != nil:
-			log.Fatalf("Error loading token: %s", err)
-		case !token.Expiry.IsZero() && token.Expiry.Before(time.Now()):
-			log.Warn("Restored token has expired! Token has been ignored.")
-		default:
-			if token.RefreshToken == "" {
-				log.Warn("Restored token has no refresh-token! Exporter will need to be re-authenticated manually.")
-			} else if token.Expiry.IsZero() {
-				log.Warn("Restored token has no expiry time! Token will be renewed immediately.")
-				token.Expiry = time.Now().Add(time.Second)
-			}
+	if !cfg.EnableWeather && !cfg.EnableHomecoach && !cfg.EnableEnergy && !cfg.EnableSecurity && !cfg.EnableDetector && !cfg.EnableHomeControl && len(cfg.PublicArea) == 0 {
+		log.Fatal("At least one collector must be enabled. Enable the Weather, HomeCoach, Energy, Security, Detector or Home+Control collector, or set NETATMO_PUBLIC_AREA for public weather stations.")
+	}
 
-			log.Infof("Loaded token from %s.", cfg.TokenFile)
-			client.InitWithToken(context.Background(), token)
-		}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-		registerSignalHandler(client, cfg.TokenFile)
-	} else {
-		log.Warn("No token-file set! Authentication will be lost on restart.")
+	if !cfg.EnableWeather {
+		log.Info("Weather station collector disabled by configuration.")
+	}
+
+	if !cfg.EnableHomecoach {
+		log.Info("HomeCoach collector disabled by configuration.")
+	}
+
+	if !cfg.EnableEnergy {
+		log.Info("Energy collector disabled by configuration.")
+	}
+
+	if !cfg.EnableSecurity {
+		log.Info("Security collector disabled by configuration.")
+	}
+
+	if !cfg.EnableDetector {
+		log.Info("Detector collector disabled by configuration.")
+	}
+
+	if !cfg.EnableHomeControl {
+		log.Info("Home+Control collector disabled by configuration.")
 	}
 
 	// Prometheus registryV1 V1 separate for Weather + HomeCoach
@@ -86,48 +81,43 @@ func main() {
 	// V2 unified registry combining Weather + HomeCoach
 	registryV2 := prometheus.NewRegistry()
 
-	var weatherReader collector.WeatherReadFunction
-	var homecoachReader collector.HomecoachReadFunction
-
-	// Weather station collector V1
-	if cfg.EnableWeather {
-		// Weather reader function for unified collector V2
-		weatherReader = client.Read
-
-		// Weather reader function V1
-		weatherMetrics := collector.NewWeatherReadFunction(log, weatherReader, cfg.RefreshInterval, cfg.StaleDuration)
-		registryV1.MustRegister(weatherMetrics)
+	// Without account names only the default client and token file are used, without an account label.
+	accountConfigs := cfg.Accounts
+	if len(accountConfigs) == 0 {
+		accountConfigs = []config.Account{
+			{
+				Netatmo:   cfg.Netatmo,
+				TokenFile: cfg.TokenFile,
+			},
+		}
 	} else {
-		log.Info("Weather station collector disabled by configuration.")
+		log.Infof("Using %d Netatmo accounts.", len(accountConfigs))
 	}
 
-	if cfg.EnableHomecoach {
-		// Homecoach reader function V1 + V2 Definition
-		homecoachReader = collector.NewHomecoachReadFunction(client.CurrentToken)
+	// The requests of all accounts using the same app count towards the budget of the app.
+	appBudgets := make(map[string]*api.Budget)
+	var accounts []*account
+	var homeAccounts []web.Account
+	for i, accountCfg := range accountConfigs {
+		appBudget, ok := appBudgets[accountCfg.Netatmo.ClientID]
+		if !ok {
+			appBudget = api.NewBudget("app", api.AppLimits)
+			appBudgets[accountCfg.Netatmo.ClientID] = appBudget
+		}
 
-		// Homecoach reader function V1
-		homecoachMetrics := collector.NewHomecoachCollector(log, homecoachReader, cfg.RefreshInterval, cfg.StaleDuration)
-		registryV1.MustRegister(homecoachMetrics)
-	} else {
-		log.Info("HomeCoach collector disabled by configuration.")
-	}
+		// The public weather stations are the same for all accounts, so they are only read once.
+		public := i == 0 && len(cfg.PublicArea) > 0
 
-	// Token metrics for V1 + V2
-	tokenMetric := token.Metric(client.CurrentToken)
-	registryV1.MustRegister(tokenMetric)
-	registryV2.MustRegister(tokenMetric)
+		a := setupAccount(ctx, cfg, accountCfg, appBudget, public, registryV1, registryV2)
+		accounts = append(accounts, a)
+		homeAccounts = append(homeAccounts, web.Account{
+			Name:     a.Name,
+			AuthPath: a.authPath(),
+			Token:    a.client.CurrentToken,
+		})
+	}
 
-	// Unified collector V2 for Weather + HomeCoach
-	unifiedCollector := collector.UnifiedCollector(
-		log,
-		weatherReader,
-		homecoachReader,
-		cfg.RefreshInterval,
-		cfg.StaleDuration,
-		cfg.EnableWeather,
-		cfg.EnableHomecoach,
-	)
-	registryV2.MustRegister(unifiedCollector)
+	registerSignalHandler(accounts)
 
 	if cfg.EnableGoMetrics {
 		log.Info("Go runtime metrics enabled.")
@@ -139,24 +129,17 @@ func main() {
 		log.Info("Go runtime metrics disabled.")
 	}
 
-	if cfg.DebugHandlers {
-		// Combined debug handler for Weather + HomeCoach
-		http.Handle("/debug/netatmo", web.DebugNetatmoHandler(log, weatherReader, homecoachReader))
-		http.Handle("/debug/token", web.DebugTokenHandler(log, client.CurrentToken))
-	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	http.Handle("/auth/authorize", web.AuthorizeHandler(cfg.ExternalURL, client, cfg.EnableWeather, cfg.EnableHomecoach))
-	http.Handle("/auth/callback", web.CallbackHandler(ctx, client, log))
-	http.Handle("/auth/settoken", web.SetTokenHandler(ctx, client, log))
-	http.Handle("/auth/deletetoken", web.DeleteTokenHandler(ctx, client, cfg.TokenFile, log))
-
 	http.Handle("/metrics/v1", promhttp.HandlerFor(registryV1, promhttp.HandlerOpts{}))
 	http.Handle("/metrics/v2", promhttp.HandlerFor(registryV2, promhttp.HandlerOpts{}))
+	http.Handle("/probe", web.ProbeHandler(log, registryV2, func(homeID, deviceID string) []string {
+		var ids []string
+		for _, a := range accounts {
+			ids = append(ids, a.store.TargetDeviceIDs(homeID, deviceID)...)
+		}
+		return ids
+	}))
 	http.Handle("/version", versionHandler(log))
-	http.Handle("/", web.HomeHandler(client.CurrentToken, log))
+	http.Handle("/", web.HomeHandler(homeAccounts, webFeatures(cfg), log))
 
 	log.Infof("Listen on %s...", cfg.Addr)
 	log.Fatal(http.ListenAndServe(cfg.Addr, nil))
@@ -177,7 +160,7 @@ func loadToken(fileName string) (*oauth2.Token, error) {
 	return &token, nil
 }
 
-func registerSignalHandler(client *netatmo.Client, fileName string) {
+func registerSignalHandler(accounts []*account) {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, signals...)
 
@@ -187,15 +170,17 @@ func registerSignalHandler(client *netatmo.Client, fileName string) {
 
 		log.Debugf("Got signal: %s", sig)
 
-		if err := saveToken(client, fileName); err != nil {
-			log.Errorf("Error persisting token: %s", err)
+		for _, a := range accounts {
+			if err := saveToken(a.log, a.client, a.TokenFile); err != nil {
+				a.log.Errorf("Error persisting token: %s", err)
+			}
 		}
 
 		os.Exit(0)
 	}()
 }
 
-func tokenUpdated(fileName string) netatmo.TokenUpdateFunc {
+func tokenUpdated(log logrus.FieldLogger, fileName string) netatmo.TokenUpdateFunc {
 	if fileName == "" {
 		return nil
 	}
@@ -209,7 +194,7 @@ func tokenUpdated(fileName string) netatmo.TokenUpdateFunc {
 	}
 }
 
-func saveToken(client *netatmo.Client, fileName string) error {
+func saveToken(log logrus.FieldLogger, client *netatmo.Client, fileName string) error {
 	token, err := client.CurrentToken()
 	switch {
 	case err == netatmo.ErrNotAuthenticated: